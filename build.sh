@@ -0,0 +1,35 @@
+#!/usr/bin/env bash
+# Builds the release archives of the action binary for every runner OS and
+# architecture into a directory. The build is reproducible: the same version
+# built from the same commit yields the same archives, so that checksums.txt
+# can pin them before the release is published.
+#
+#   ./build.sh v1.2.0 dist && (cd dist && sha256sum *.tar.gz) >> checksums.txt
+set -euo pipefail
+
+version="${1:?usage: build.sh <version> <dir>}"
+dir="${2:?usage: build.sh <version> <dir>}"
+binary=gha-register-build-action
+source_dir="$(cd "$(dirname "$0")" && pwd)"
+
+export CGO_ENABLED=0
+export GOTOOLCHAIN
+GOTOOLCHAIN=$(awk '$1 == "toolchain" { print $2 }' "$source_dir/go.mod")
+
+build=$(mktemp -d)
+trap 'rm -rf "$build"' EXIT
+mkdir -p "$dir"
+for target in linux/amd64 linux/arm64 darwin/amd64 darwin/arm64 windows/amd64 windows/arm64; do
+  goos="${target%/*}"
+  goarch="${target#*/}"
+  executable="$binary"
+  if [ "$goos" = windows ]; then
+    executable="$binary.exe"
+  fi
+  mkdir -p "$build/$goos-$goarch"
+  (cd "$source_dir" && GOOS="$goos" GOARCH="$goarch" go build -trimpath -buildvcs=false \
+    -ldflags "-s -w -X gha-register-build-artifact/cmd.version=$version" \
+    -o "$build/$goos-$goarch/$executable" .)
+  tar --sort=name --mtime=@0 --owner=0 --group=0 --numeric-owner -C "$build/$goos-$goarch" -cf - "$executable" |
+    gzip -n > "$dir/${binary}_${version}_${goos}_${goarch}.tar.gz"
+done
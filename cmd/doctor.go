@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"gha-register-build-artifact/internal/artifacts"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that the job can register artifacts",
+		Long:  "Check the workflow environment, the id-token permission, the DNS and TLS reachability of the CloudBees API, the OIDC token claims and the token exchange, without sending an event. Fails when a check fails",
+		RunE:  doctor,
+	}
+	doctorOutput string
+)
+
+func init() {
+	doctorCmd.Flags().StringVarP(&doctorOutput, "output", "o", "text", "The output format: text or json")
+	cmd.AddCommand(doctorCmd)
+}
+
+func doctor(_ *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unknown arguments: %v", args)
+	}
+	if doctorOutput != "text" && doctorOutput != "json" {
+		return fmt.Errorf("invalid output format %q, expected text or json", doctorOutput)
+	}
+	report := cfg.Doctor(newSignalContext())
+	if err := printChecks(os.Stdout, report, doctorOutput); err != nil {
+		return err
+	}
+	if failed := report.Failed(); failed > 0 {
+		return fmt.Errorf("doctor found %d failing check(s)", failed)
+	}
+	return nil
+}
+
+func printChecks(w io.Writer, report *artifacts.DoctorReport, format string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, check := range report.Checks {
+		fmt.Fprintf(table, "[%s]\t%s\t%s\n", strings.ToUpper(check.Status), check.Name, check.Message)
+		if check.Hint != "" && check.Status != artifacts.CheckPass && check.Status != artifacts.CheckSkip {
+			fmt.Fprintf(table, "\t\thint: %s\n", check.Hint)
+		}
+	}
+	return table.Flush()
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"bytes"
+	"gha-register-build-artifact/internal/artifacts"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PrintChecks(t *testing.T) {
+	report := &artifacts.DoctorReport{Checks: []artifacts.Check{
+		{Name: artifacts.CheckEnvironment, Status: artifacts.CheckPass, Message: "set"},
+		{Name: artifacts.CheckIdTokenPermission, Status: artifacts.CheckFail, Message: "not set", Hint: "grant id-token: write"},
+		{Name: artifacts.CheckOidcToken, Status: artifacts.CheckSkip, Message: "no token"},
+	}}
+
+	var buf bytes.Buffer
+	assert.Nil(t, printChecks(&buf, report, "text"))
+	assert.Equal(t, "[PASS]  environment          set\n"+
+		"[FAIL]  id-token-permission  not set\n"+
+		"                             hint: grant id-token: write\n"+
+		"[SKIP]  oidc-token           no token\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, printChecks(&buf, report, "json"))
+	assert.Contains(t, buf.String(), `"hint": "grant id-token: write"`)
+}
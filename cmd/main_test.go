@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"gha-register-build-artifact/internal/artifacts"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// On a runner the state file would turn repeated test registrations into
+	// no-ops and the results would be written to the step outputs.
+	os.Unsetenv(artifacts.RunnerTemp)
+	os.Unsetenv(artifacts.GithubOutput)
+	os.Exit(m.Run())
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gha-register-build-artifact/internal/artifacts"
+	"gha-register-build-artifact/internal/platformtest"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mockServerCmd = &cobra.Command{
+		Use:   "mock-server",
+		Short: "Run a local mock of the CloudBees platform",
+		Long:  "Run a local mock of the CloudBees platform implementing the OIDC token, token exchange and external events endpoints for development and tests",
+		RunE:  mockServer,
+	}
+	mockServerAddr   string
+	mockServerConfig string
+)
+
+func init() {
+	mockServerCmd.Flags().StringVar(&mockServerAddr, "addr", "127.0.0.1:8080", "The address to listen on")
+	mockServerCmd.Flags().StringVar(&mockServerConfig, "config", "", "Path of a JSON file with the issuer, subject, request-token and faults to inject")
+	cmd.AddCommand(mockServerCmd)
+}
+
+func mockServer(_ *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unknown arguments: %v", args)
+	}
+	config := platformtest.Config{RequestToken: platformtest.DefaultRequestToken}
+	if mockServerConfig != "" {
+		data, err := os.ReadFile(mockServerConfig)
+		if err != nil {
+			return fmt.Errorf("failed to read mock server config: %w", err)
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return fmt.Errorf("failed to parse mock server config: %w", err)
+		}
+	}
+	platform, err := platformtest.NewPlatform(config)
+	if err != nil {
+		return err
+	}
+
+	platform.OnEvent(func(event cloudevents.Event) {
+		slog.Info("Received event", artifacts.LogEventId, event.ID(), "type", event.Type(), "subject", event.Subject())
+		slog.Debug("Received event data", artifacts.LogEventId, event.ID(), "data", string(event.Data()))
+	})
+
+	listener, err := net.Listen("tcp", mockServerAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", mockServerAddr, err)
+	}
+	url := "http://" + listener.Addr().String()
+	slog.Info("Mock CloudBees platform listening", "url", url)
+	fmt.Println("Use the following environment to register against it:")
+	fmt.Printf("  %s=%s\n", artifacts.CloudbeesApiUrl, url)
+	fmt.Printf("  %s=%s\n", artifacts.ActionIdTokenRequestUrl, url+platformtest.OIDCPath)
+	fmt.Printf("  %s=%s\n", artifacts.ActionIdTokenRequestToken, config.RequestToken)
+	fmt.Printf("  %s=%s\n", artifacts.GithubApiUrl, url)
+
+	server := &http.Server{Handler: platform}
+	newContext, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	go func() {
+		<-newContext.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("Mock CloudBees platform stopped", "events", len(platform.Events()))
+	return nil
+}
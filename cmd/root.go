@@ -2,10 +2,14 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"gha-register-build-artifact/internal/artifacts"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -16,16 +20,74 @@ var (
 		Short: "Publish the build artifact metadata to CloudBees Build Platform",
 		Long:  "Publish the build artifact metadata to CloudBees Build Platform",
 		RunE:  run,
+
+		PersistentPreRunE: configure,
+		SilenceErrors:     true,
+		SilenceUsage:      true,
 	}
-	cfg artifacts.Config
+	// version is set at build time with -ldflags "-X gha-register-build-artifact/cmd.version=v1.2.0".
+	version         = "dev"
+	cfg             artifacts.Config
+	logOptions      artifacts.LogOptions
+	otlpEndpoint    string
+	toolchains      []string
+	contains        []string
+	builtFrom       []string
+	dependsOn       []string
+	globs           []string
+	shutdownTracing func(context.Context) error
 )
 
 func Execute() error {
-	return cmd.Execute()
+	err := cmd.Execute()
+	if shutdownTracing != nil {
+		if shutdownErr := shutdownTracing(context.Background()); shutdownErr != nil {
+			slog.Warn("Failed to flush traces", "error", shutdownErr)
+		}
+	}
+	return err
 }
 
 func init() {
+	cmd.Version = version
 	setDefaultValues(&cfg)
+	logOptions.Format = os.Getenv(artifacts.LogFormat)
+	logOptions.Level = os.Getenv(artifacts.LogLevel)
+	cmd.PersistentFlags().StringVar(&logOptions.Format, "log-format", logOptions.Format, "The log format: text or json")
+	cmd.PersistentFlags().StringVar(&logOptions.Level, "log-level", logOptions.Level, "The log level: debug, info, warn or error")
+	cmd.PersistentFlags().BoolVarP(&logOptions.Verbose, "verbose", "v", false, "Enable debug logs")
+	cmd.PersistentFlags().BoolVarP(&logOptions.Quiet, "quiet", "q", false, "Only log errors")
+	cmd.PersistentFlags().StringVar(&cfg.OidcIssuer, "oidc-issuer", cfg.OidcIssuer, "Fail before the token exchange when the GitHub OIDC token has another issuer. With --ghes-token-exchange it is derived from GITHUB_SERVER_URL")
+	cmd.PersistentFlags().BoolVar(&cfg.GhesTokenExchange, "ghes-token-exchange", cfg.GhesTokenExchange, "Exchange GitHub Enterprise Server OIDC tokens with the GITHUB_ENTERPRISE provider, server url and issuer. Requires a platform that supports GHES")
+	cmd.PersistentFlags().StringVar(&otlpEndpoint, "otlp-endpoint", os.Getenv(artifacts.OtelExporterOtlpEndpoint), "Export traces via OTLP/HTTP to this endpoint, e.g. http://localhost:4318. Tracing is off when empty")
+	cmd.Flags().BoolVar(&cfg.Provenance, "provenance", cfg.Provenance, "Generate a SLSA provenance statement for the artifact and include it in the event")
+	cmd.Flags().StringVar(&cfg.ProvenancePath, "provenance-path", cfg.ProvenancePath, "Write the generated provenance statement to this file")
+	cmd.Flags().StringVar(&cfg.SigningKey, "signing-key", cfg.SigningKey, "Sign the event data with this ed25519 or ECDSA private key (PEM file or content)")
+	cmd.Flags().StringVar(&cfg.EventPath, "event-path", cfg.EventPath, "Write the CloudEvent sent to the platform to this file")
+	cmd.Flags().BoolVar(&cfg.Infer, "infer", cfg.Infer, "Infer missing artifact name, version, url and type from a docker reference or build files")
+	cmd.Flags().StringVar(&cfg.InferDir, "infer-dir", cfg.InferDir, "The directory containing the build files used by --infer")
+	cmd.Flags().BoolVar(&cfg.Idempotent, "idempotent", cfg.Idempotent, "Derive the event ID from the run and artifact so that job re-runs send the same event")
+	cmd.Flags().BoolVar(&cfg.Force, "force", cfg.Force, "Register the artifact even if it was already registered in this job")
+	cmd.Flags().StringVar(&cfg.StatePath, "state-path", cfg.StatePath, "The file recording the artifacts registered in this job, by default in RUNNER_TEMP")
+	cmd.Flags().StringVar(&cfg.PolicyPath, "policy", cfg.PolicyPath, "Check the artifact against this YAML or JSON policy file before registering it")
+	cmd.Flags().StringVar(&cfg.PolicyMode, "policy-mode", cfg.PolicyMode, "enforce fails on policy violations, audit only logs them")
+	cmd.Flags().BoolVar(&cfg.GitMetadata, "git-metadata", cfg.GitMetadata, "Read the commit, message and author from the local git repository when the workflow does not provide them")
+	cmd.Flags().StringArrayVar(&toolchains, "toolchain", envValues(artifacts.ArtifactToolchains), "Record the version printed by a toolchain probe, as name=command, e.g. go=go version")
+	cmd.Flags().BoolVar(&cfg.Platforms, "platforms", cfg.Platforms, "Register each platform image of an image index, linked to the index digest")
+	cmd.Flags().StringVar(&cfg.RegistryUsername, "registry-username", cfg.RegistryUsername, "The username used to read the image index from the registry")
+	cmd.Flags().StringVar(&cfg.RelationshipsPath, "relationships", cfg.RelationshipsPath, "A YAML or JSON manifest of the contains, built-from and depends-on relationships of the artifact")
+	cmd.Flags().StringArrayVar(&contains, "contains", envValues(artifacts.ArtifactContains), "An artifact bundled in this one, as name@version, name@digest or digest")
+	cmd.Flags().StringArrayVar(&builtFrom, "built-from", envValues(artifacts.ArtifactBuiltFrom), "An artifact this one was built from, as name@version, name@digest or digest")
+	cmd.Flags().StringArrayVar(&dependsOn, "depends-on", envValues(artifacts.ArtifactDependsOn), "An artifact this one depends on, as name@version, name@digest or digest")
+	cmd.Flags().StringVar(&cfg.FromDir, "from-dir", cfg.FromDir, "Register each file of this directory matching --glob instead of a single artifact")
+	cmd.Flags().StringArrayVar(&globs, "glob", envValues(artifacts.ArtifactGlob), "The files of --from-dir or the assets of --from-release to register, e.g. *.tar.gz. Globs without a slash match the file name")
+	cmd.Flags().StringVar(&cfg.FilenameTemplate, "filename-template", cfg.FilenameTemplate, "Read the artifact name, version and other placeholders from the file name, e.g. {name}_{version}_{os}_{arch}.{ext}")
+	cmd.Flags().StringVar(&cfg.UrlTemplate, "url-template", cfg.UrlTemplate, "Build the artifact url of each file, e.g. https://github.com/owner/repo/releases/download/v{version}/{file}")
+	cmd.Flags().BoolVar(&cfg.FromRelease, "from-release", cfg.FromRelease, "Register each asset of a GitHub release matching --glob instead of a single artifact")
+	cmd.Flags().StringVar(&cfg.ReleaseTag, "release-tag", cfg.ReleaseTag, "The tag of the release registered by --from-release, by default the tag of GITHUB_REF")
+	cmd.Flags().IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "How many artifacts of --from-dir or --from-release are registered in parallel")
+	cmd.Flags().Float64Var(&cfg.RateLimit, "rate-limit", cfg.RateLimit, "The maximum number of events per second sent by --from-dir or --from-release, 0 for no limit. The rate is lowered when the platform responds with 429")
+	cmd.Flags().BoolVar(&cfg.VerifyRun, "verify-run", cfg.VerifyRun, "Verify with the GitHub API that the workflow run is in progress and published the artifact")
 }
 
 func setDefaultValues(cfg *artifacts.Config) {
@@ -49,12 +111,159 @@ func setDefaultValues(cfg *artifacts.Config) {
 	} else {
 		cfg.ArtifactLabel = ""
 	}
+
+	provenance, err := strconv.ParseBool(os.Getenv(artifacts.ArtifactProvenance))
+	cfg.Provenance = err == nil && provenance
+
+	cfg.ProvenancePath = os.Getenv(artifacts.ArtifactProvenancePath)
+
+	cfg.SigningKey = os.Getenv(artifacts.ArtifactSigningKey)
+
+	cfg.EventPath = os.Getenv(artifacts.ArtifactEventPath)
+
+	infer, err := strconv.ParseBool(os.Getenv(artifacts.ArtifactInfer))
+	cfg.Infer = err == nil && infer
+
+	cfg.InferDir = os.Getenv(artifacts.ArtifactInferDir)
+
+	idempotent, err := strconv.ParseBool(os.Getenv(artifacts.ArtifactIdempotent))
+	cfg.Idempotent = err == nil && idempotent
+
+	force, err := strconv.ParseBool(os.Getenv(artifacts.ArtifactForce))
+	cfg.Force = err == nil && force
+
+	cfg.StatePath = os.Getenv(artifacts.ArtifactStatePath)
+
+	verifyRun, err := strconv.ParseBool(os.Getenv(artifacts.ArtifactVerifyRun))
+	cfg.VerifyRun = err == nil && verifyRun
+
+	cfg.GithubToken = os.Getenv(artifacts.GithubToken)
+
+	cfg.GithubApiUrl = os.Getenv(artifacts.GithubApiUrl)
+
+	cfg.OidcIssuer = os.Getenv(artifacts.ArtifactOidcIssuer)
+
+	ghesTokenExchange, err := strconv.ParseBool(os.Getenv(artifacts.ArtifactGhesTokenExchange))
+	cfg.GhesTokenExchange = err == nil && ghesTokenExchange
+
+	platforms, err := strconv.ParseBool(os.Getenv(artifacts.ArtifactPlatforms))
+	cfg.Platforms = err == nil && platforms
+
+	cfg.RegistryUsername = os.Getenv(artifacts.ArtifactRegistryUsername)
+
+	cfg.RegistryPassword = os.Getenv(artifacts.ArtifactRegistryPassword)
+
+	cfg.RelationshipsPath = os.Getenv(artifacts.ArtifactRelationships)
+
+	cfg.FromDir = os.Getenv(artifacts.ArtifactFromDir)
+
+	cfg.FilenameTemplate = os.Getenv(artifacts.ArtifactFilenameTemplate)
+
+	cfg.UrlTemplate = os.Getenv(artifacts.ArtifactUrlTemplate)
+
+	fromRelease, err := strconv.ParseBool(os.Getenv(artifacts.ArtifactFromRelease))
+	cfg.FromRelease = err == nil && fromRelease
+
+	cfg.ReleaseTag = os.Getenv(artifacts.ArtifactReleaseTag)
+
+	concurrency, err := strconv.Atoi(os.Getenv(artifacts.ArtifactConcurrency))
+	if err != nil {
+		concurrency = artifacts.DefaultConcurrency
+	}
+	cfg.Concurrency = concurrency
+
+	rateLimit, err := strconv.ParseFloat(os.Getenv(artifacts.ArtifactRateLimit), 64)
+	if err != nil {
+		rateLimit = artifacts.DefaultRateLimit
+	}
+	cfg.RateLimit = rateLimit
+
+	cfg.PolicyPath = os.Getenv(artifacts.ArtifactPolicy)
+
+	gitMetadata, err := strconv.ParseBool(os.Getenv(artifacts.ArtifactGitMetadata))
+	cfg.GitMetadata = err == nil && gitMetadata
+
+	cfg.PolicyMode = os.Getenv(artifacts.ArtifactPolicyMode)
+	if cfg.PolicyMode == "" {
+		cfg.PolicyMode = artifacts.PolicyModeEnforce
+	}
+}
+
+func configure(command *cobra.Command, _ []string) error {
+	err := artifacts.ConfigureLogger(os.Stderr, logOptions)
+	if err != nil {
+		return err
+	}
+	shutdownTracing, err = artifacts.ConfigureTracing(command.Context(), otlpEndpoint)
+	return err
 }
 
 func run(_ *cobra.Command, args []string) error {
 	if len(args) > 0 {
 		return fmt.Errorf("unknown arguments: %v", args)
 	}
+	probes, err := artifacts.ParseToolchainProbes(toolchains...)
+	if err != nil {
+		return err
+	}
+	cfg.ToolchainProbes = probes
+	relationships, err := artifacts.ParseRelationships(contains, builtFrom, dependsOn)
+	if err != nil {
+		return err
+	}
+	cfg.Relationships = relationships
+
+	if cfg.FromDir != "" {
+		return runBatch(cfg.RunFromDir)
+	}
+	if cfg.FromRelease {
+		return runBatch(cfg.RunFromRelease)
+	}
+	result, err := cfg.Run(newSignalContext())
+	if result == nil {
+		return err
+	}
+	// A failed platform of an image index returns the registrations so far.
+	fmt.Println(artifacts.PrettyPrint(result))
+	if outputErr := result.WriteGithubOutputs(); outputErr != nil {
+		return errors.Join(err, outputErr)
+	}
+	return err
+}
+
+// runBatch registers the artifacts listed by a --from-dir or --from-release
+// mode and prints the registrations.
+func runBatch(runArtifacts func(context.Context) ([]*artifacts.RegistrationResult, error)) error {
+	cfg.Globs = nil
+	for _, value := range globs {
+		for _, glob := range strings.Split(value, "\n") {
+			if glob = strings.TrimSpace(glob); glob != "" {
+				cfg.Globs = append(cfg.Globs, glob)
+			}
+		}
+	}
+
+	results, err := runArtifacts(newSignalContext())
+	if results == nil {
+		return err
+	}
+	fmt.Println(artifacts.PrettyPrint(results))
+	if outputErr := artifacts.WriteBatchGithubOutputs(results); outputErr != nil {
+		return errors.Join(err, outputErr)
+	}
+	return err
+}
+
+// envValues returns the value of a multi-line environment variable as the
+// default of a repeatable flag.
+func envValues(key string) []string {
+	if value := os.Getenv(key); value != "" {
+		return []string{value}
+	}
+	return nil
+}
+
+func newSignalContext() context.Context {
 	newContext, cancel := context.WithCancel(context.Background())
 	osChannel := make(chan os.Signal, 1)
 	signal.Notify(osChannel, os.Interrupt)
@@ -62,6 +271,5 @@ func run(_ *cobra.Command, args []string) error {
 		<-osChannel
 		cancel()
 	}()
-
-	return cfg.Run(newContext)
+	return newContext
 }
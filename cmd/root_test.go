@@ -1,12 +1,11 @@
 package cmd
 
 import (
-	"fmt"
 	"gha-register-build-artifact/internal/artifacts"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
 	"net/http"
-	"net/http/httptest"
 	"os"
-	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -41,27 +40,13 @@ func Test_Run(t *testing.T) {
 	os.Setenv(artifacts.GithubJobName, "testjob")
 	os.Setenv(artifacts.ArtifactLabel, "labelA,labelB,labelC")
 
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("Mock Request URL:", r.URL.String())
-		switch {
-		case r.Method == "GET" && strings.HasPrefix(r.URL.String(), "/?audience="):
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"value": "mock-oidc-token"}`))
-		case r.Method == "POST" && r.URL.Path == "/token-exchange/external-oidc-id-token":
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"accessToken": "mock-cbp-token"}`))
-		case r.Method == "POST" && r.URL.Path == "/v3/external-events":
-			w.WriteHeader(http.StatusOK)
-		default:
-			http.Error(w, "unexpected request: "+r.URL.Path, http.StatusNotFound)
-		}
-	}))
-	defer ts.Close()
+	server := testserver.New(t, platformtest.Config{})
 
-	os.Setenv(artifacts.CloudbeesApiUrl, ts.URL)
-	os.Setenv(artifacts.ActionIdTokenRequestUrl, ts.URL)
+	os.Setenv(artifacts.CloudbeesApiUrl, server.URL)
+	os.Setenv(artifacts.ActionIdTokenRequestUrl, server.OIDCUrl())
 	err := run(nil, nil)
 	assert.Nil(t, err)
+	assert.Len(t, server.Events(), 1)
 }
 
 func Test_Failure(t *testing.T) {
@@ -77,25 +62,12 @@ func Test_Failure(t *testing.T) {
 	os.Setenv(artifacts.GithubJobName, "testjob")
 	os.Setenv(artifacts.ArtifactLabel, "labelA,labelB,labelC")
 
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("Mock Request URL:", r.URL.String())
-		switch {
-		case r.Method == "GET" && strings.HasPrefix(r.URL.String(), "/?audience="):
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"value": "mock-oidc-token"}`))
-		case r.Method == "POST" && r.URL.Path == "/token-exchange/external-oidc-id-token":
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"accessToken": "mock-cbp-token"}`))
-		case r.Method == "POST" && r.URL.Path == "/v3/external-events":
-			w.WriteHeader(http.StatusBadGateway)
-		default:
-			http.Error(w, "unexpected request: "+r.URL.Path, http.StatusNotFound)
-		}
-	}))
-	defer ts.Close()
+	server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+		platformtest.EventsEndpoint: {Status: http.StatusBadGateway},
+	}})
 
-	os.Setenv(artifacts.CloudbeesApiUrl, ts.URL)
-	os.Setenv(artifacts.ActionIdTokenRequestUrl, ts.URL)
+	os.Setenv(artifacts.CloudbeesApiUrl, server.URL)
+	os.Setenv(artifacts.ActionIdTokenRequestUrl, server.OIDCUrl())
 	err := run(nil, nil)
 	assert.Contains(t, err.Error(), "error sending CloudEvent to platform")
 }
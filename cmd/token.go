@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"gha-register-build-artifact/internal/artifacts"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	tokenCmd = &cobra.Command{
+		Use:   "token",
+		Short: "Exchange the OIDC token of the job for a CloudBees access token",
+		Long:  "Exchange the OIDC token of the job for a short-lived CloudBees access token for other steps calling the CloudBees API. The token is masked and written to the token step output, or printed as an exec credential",
+		RunE:  token,
+	}
+	tokenOutput string
+)
+
+func init() {
+	tokenCmd.Flags().StringVarP(&tokenOutput, "output", "o", defaultTokenOutput(), "Where to write the token: github for the token step output, or exec-credential for stdout")
+	cmd.AddCommand(tokenCmd)
+}
+
+func token(_ *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unknown arguments: %v", args)
+	}
+	if tokenOutput != "github" && tokenOutput != "exec-credential" {
+		return fmt.Errorf("invalid output %q, expected github or exec-credential", tokenOutput)
+	}
+	accessToken, err := cfg.Token(newSignalContext())
+	if err != nil {
+		return err
+	}
+	if tokenOutput == "github" {
+		return accessToken.WriteGithubOutputs(os.Stdout)
+	}
+	return json.NewEncoder(os.Stdout).Encode(accessToken.ExecCredential())
+}
+
+// defaultTokenOutput writes the token to the step outputs in GitHub Actions.
+func defaultTokenOutput() string {
+	if os.Getenv(artifacts.GithubOutput) != "" {
+		return "github"
+	}
+	return "exec-credential"
+}
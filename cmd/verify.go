@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"gha-register-build-artifact/internal/artifacts"
+	"log/slog"
+	"os"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the signature of a stored CloudEvent",
+		Long:  "Verify the detached signature of a CloudEvent written with --event-path against a public key",
+		RunE:  verify,
+	}
+	verifyEventPath string
+	verifyPublicKey string
+)
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifyEventPath, "event", "", "Path of the stored CloudEvent JSON")
+	verifyCmd.Flags().StringVar(&verifyPublicKey, "public-key", "", "The ed25519 or ECDSA public key (PEM file or content)")
+	_ = verifyCmd.MarkFlagRequired("event")
+	_ = verifyCmd.MarkFlagRequired("public-key")
+	cmd.AddCommand(verifyCmd)
+}
+
+func verify(_ *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unknown arguments: %v", args)
+	}
+	data, err := os.ReadFile(verifyEventPath)
+	if err != nil {
+		return fmt.Errorf("failed to read event: %w", err)
+	}
+	cloudEvent := cloudevents.NewEvent()
+	if err := json.Unmarshal(data, &cloudEvent); err != nil {
+		return fmt.Errorf("failed to parse event: %w", err)
+	}
+	publicKey, err := artifacts.LoadPublicKey(verifyPublicKey)
+	if err != nil {
+		return err
+	}
+	if err := artifacts.VerifyCloudEvent(cloudEvent, publicKey); err != nil {
+		return err
+	}
+	slog.Info("Event signature verified successfully", artifacts.LogEventId, cloudEvent.ID())
+	return nil
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Verify(t *testing.T) {
+	publicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(publicKey)
+	dir := t.TempDir()
+	verifyPublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	cloudEvent := cloudevents.NewEvent()
+	cloudEvent.SetID("test")
+	cloudEvent.SetType("test")
+	cloudEvent.SetSource("test")
+	eventJSON, _ := json.Marshal(cloudEvent)
+	verifyEventPath = filepath.Join(dir, "event.json")
+	os.WriteFile(verifyEventPath, eventJSON, 0644)
+
+	err := verify(nil, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, "event is not signed", err.Error())
+
+	verifyEventPath = filepath.Join(dir, "missing.json")
+	err = verify(nil, nil)
+	assert.Contains(t, err.Error(), "failed to read event")
+
+	err = verify(nil, []string{"test"})
+	assert.Contains(t, err.Error(), "unknown arguments:")
+}
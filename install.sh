@@ -0,0 +1,79 @@
+#!/usr/bin/env bash
+# Installs the release binary of the action for the runner OS and architecture
+# into the tool cache, after verifying it against the checksums pinned in
+# checksums.txt of the action ref. Used by action.yml so that the action runs
+# without Docker on every runner OS. Branches, commits and local checkouts of
+# the action have no release binary: they are built from source when Go is set
+# up, and run the latest release otherwise.
+set -euo pipefail
+
+binary=gha-register-build-action
+repository="${INSTALL_REPOSITORY:-HemalaDev57/TestAction}"
+version="${INSTALL_VERSION:-}"
+ref="${INSTALL_REF:-}"
+server_url="${GITHUB_SERVER_URL:-https://github.com}"
+action_path="${GITHUB_ACTION_PATH:-$(dirname "$0")}"
+
+case "${RUNNER_OS:-$(uname -s)}" in
+  Linux) os=linux ;;
+  macOS | Darwin) os=darwin ;;
+  Windows | MINGW* | MSYS*) os=windows ;;
+  *) echo "::error::Unsupported runner OS ${RUNNER_OS:-$(uname -s)}" && exit 1 ;;
+esac
+case "${RUNNER_ARCH:-$(uname -m)}" in
+  X64 | x86_64 | amd64) arch=amd64 ;;
+  ARM64 | arm64 | aarch64) arch=arm64 ;;
+  *) echo "::error::Unsupported runner architecture ${RUNNER_ARCH:-$(uname -m)}" && exit 1 ;;
+esac
+executable="$binary"
+if [ "$os" = windows ]; then
+  executable="$binary.exe"
+fi
+
+if [ -z "$version" ] && [[ "$ref" =~ ^v[0-9] ]]; then
+  version="$ref"
+fi
+if [ -z "$version" ] && command -v go > /dev/null && [ -f "$action_path/go.mod" ]; then
+  dir="${RUNNER_TEMP:-/tmp}/$binary/source"
+  (cd "$action_path" && CGO_ENABLED=0 go build -trimpath -ldflags "-X gha-register-build-artifact/cmd.version=${ref:-dev}" -o "$dir/$executable" .)
+  echo "Built $binary ${ref:-dev} for $os/$arch from source in $dir"
+  echo "path=$dir/$executable" >> "$GITHUB_OUTPUT"
+  exit 0
+fi
+if [ -z "$version" ]; then
+  echo "::notice::The action ref ${ref:-of the local checkout} is not a release tag, running the latest release. Set cli-version or set up Go to build the ref from source"
+  version=latest
+fi
+if [ "$version" = latest ]; then
+  if ! version=$(curl -fsSLI -o /dev/null -w '%{url_effective}' "$server_url/$repository/releases/latest"); then
+    echo "::error::Failed to resolve the latest release of $repository" && exit 1
+  fi
+  version="${version##*/}"
+fi
+
+dir="${RUNNER_TOOL_CACHE:-${RUNNER_TEMP:-/tmp}}/$binary/$version/$arch"
+if [ ! -x "$dir/$executable" ]; then
+  archive="${binary}_${version}_${os}_${arch}.tar.gz"
+  expected=$(awk -v name="$archive" '$2 == name || $2 == "*" name { print $1 }' "$action_path/checksums.txt")
+  if [ -z "$expected" ]; then
+    echo "::error::checksums.txt of the action ref pins no checksum for $archive, use a ref of the action that includes $version" && exit 1
+  fi
+  tmp=$(mktemp -d)
+  trap 'rm -rf "$tmp"' EXIT
+  curl -fsSL --retry 3 -o "$tmp/$archive" "$server_url/$repository/releases/download/$version/$archive"
+
+  if command -v sha256sum > /dev/null; then
+    actual=$(sha256sum "$tmp/$archive" | awk '{ print $1 }')
+  else
+    actual=$(shasum -a 256 "$tmp/$archive" | awk '{ print $1 }')
+  fi
+  if [ "$actual" != "$expected" ]; then
+    echo "::error::Checksum mismatch for $archive: expected $expected, got $actual" && exit 1
+  fi
+
+  mkdir -p "$dir"
+  tar -xzf "$tmp/$archive" -C "$dir" "$executable"
+fi
+
+echo "Installed $binary $version for $os/$arch in $dir"
+echo "path=$dir/$executable" >> "$GITHUB_OUTPUT"
@@ -0,0 +1,189 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// RunBatch registers the artifacts with the settings of the config and returns
+// the registrations in the order of the artifacts. The steps shared by the
+// artifacts run once before the registrations, and their failure fails the
+// batch. Concurrency workers register the artifacts, sending at most
+// RateLimit events per second and slowing down when the platform responds
+// with 429. A failed registration does not stop the others; its result holds
+// the error, which is also joined to the returned error.
+func (config *Config) RunBatch(ctx context.Context, artifacts []ArtifactInfo) ([]*RegistrationResult, error) {
+	logger := stepLogger(StepBatch)
+	concurrency := min(max(config.Concurrency, 1), max(len(artifacts), 1))
+	limiter := newRateLimiter(config.RateLimit, concurrency)
+	logger.Info("Registering artifacts", "artifacts", len(artifacts), "concurrency", concurrency, "rate_limit", config.RateLimit)
+	batchConfig, err := prepareBatch(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	results := make([]*RegistrationResult, len(artifacts))
+	errs := make([]error, len(artifacts))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				artifact := artifacts[i]
+				artifactConfig := artifactConfig(batchConfig, artifact)
+				artifactConfig.limiter = limiter
+				result, err := artifactConfig.Run(ctx)
+				if err != nil {
+					errs[i] = fmt.Errorf("failed to register %s@%s: %w", artifact.ArtifactName, artifact.ArtifactVersion, err)
+					result = &RegistrationResult{Error: err.Error()}
+				}
+				result.ArtifactName = artifact.ArtifactName
+				result.ArtifactVersion = artifact.ArtifactVersion
+				result.ArtifactUrl = artifact.ArtifactUrl
+				results[i] = result
+			}
+		}()
+	}
+	for i := range artifacts {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var registered, skipped, failed int
+	for i, result := range results {
+		switch {
+		case errs[i] != nil:
+			failed++
+		case result.Skipped:
+			skipped++
+		default:
+			registered++
+		}
+	}
+	logger.Info("Registered artifacts", "registered", registered, "skipped", skipped, "failed", failed, "duration", time.Since(started).Round(time.Millisecond))
+	return results, errors.Join(errs...)
+}
+
+// prepareBatch returns a copy of the config with the steps shared by the
+// artifacts of a batch done: the toolchain probes, the policy load, the
+// verification of the workflow run and the token exchange.
+func prepareBatch(ctx context.Context, config *Config) (batchConfig *Config, err error) {
+	batchConfig = new(Config)
+	*batchConfig = *config
+	err = setRunEnvVars(batchConfig)
+	if err != nil {
+		return nil, err
+	}
+	if batchConfig.toolchains == nil {
+		batchConfig.toolchains = probeToolchains(ctx, batchConfig)
+	}
+	if batchConfig.PolicyPath != "" {
+		batchConfig.policy, err = LoadPolicy(batchConfig.PolicyPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if batchConfig.VerifyRun {
+		batchConfig.workflowRun, err = getWorkflowRun(ctx, batchConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	batchConfig.token = &sharedToken{}
+	_, err = batchConfig.token.get(ctx, batchConfig)
+	if err != nil {
+		return nil, err
+	}
+	return batchConfig, nil
+}
+
+const tokenExpiryMargin = time.Minute
+
+// sharedToken is the access token shared by the registrations of a batch or of
+// an image index and its platforms. It is renewed when it is about to expire
+// or the platform rejects it.
+type sharedToken struct {
+	mu     sync.Mutex
+	value  string
+	expiry time.Time
+}
+
+func (t *sharedToken) get(ctx context.Context, config *Config) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.value != "" && (t.expiry.IsZero() || time.Until(t.expiry) > tokenExpiryMargin) {
+		return t.value, nil
+	}
+	return t.renew(ctx, config)
+}
+
+// refresh renews the token rejected by the platform, unless another
+// registration renewed it already.
+func (t *sharedToken) refresh(ctx context.Context, config *Config, rejected string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.value != rejected {
+		return t.value, nil
+	}
+	return t.renew(ctx, config)
+}
+
+func (t *sharedToken) renew(ctx context.Context, config *Config) (string, error) {
+	value, err := authenticate(ctx, config)
+	if err != nil {
+		return "", err
+	}
+	t.value, t.expiry = value, time.Time{}
+	if claims, err := decodeClaims(value); err == nil && claims.Expiry != 0 {
+		t.expiry = time.Unix(claims.Expiry, 0)
+	}
+	return value, nil
+}
+
+// setRunEnvVars sets the workflow run and the CloudBees API of a batch from
+// the environment. validate sets them again with the artifact fields.
+func setRunEnvVars(cfg *Config) error {
+	for _, env := range []struct {
+		key   string
+		field *string
+	}{
+		{GithubRunId, &cfg.GhaRunId},
+		{GithubRunAttempt, &cfg.GhaRunAttempt},
+		{CloudbeesApiUrl, &cfg.CloudBeesApiUrl},
+		{GithubRepository, &cfg.GhaRepository},
+	} {
+		value := os.Getenv(env.key)
+		if value == "" {
+			return fmt.Errorf(env.key + " is not set in the environment")
+		}
+		*env.field = value
+	}
+	cfg.GhaServerUrl = os.Getenv(GithubServerUrl)
+	return nil
+}
+
+func artifactConfig(config *Config, artifact ArtifactInfo) *Config {
+	artifactConfig := *config
+	artifactConfig.ArtifactName = artifact.ArtifactName
+	artifactConfig.ArtifactUrl = artifact.ArtifactUrl
+	artifactConfig.ArtifactVersion = artifact.ArtifactVersion
+	artifactConfig.ArtifactDigest = artifact.ArtifactDigest
+	artifactConfig.ArtifactSize = artifact.ArtifactSize
+	if artifact.ArtifactType != "" {
+		artifactConfig.ArtifactType = artifact.ArtifactType
+	}
+	artifactConfig.Infer = false
+	artifactConfig.Platforms = false
+	artifactConfig.FromDir = ""
+	artifactConfig.FromRelease = false
+	artifactConfig.batch = true
+	return &artifactConfig
+}
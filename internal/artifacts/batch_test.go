@@ -0,0 +1,196 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func batchArtifacts(count int) []ArtifactInfo {
+	artifacts := make([]ArtifactInfo, count)
+	for i := range artifacts {
+		artifacts[i] = ArtifactInfo{
+			ArtifactName:    fmt.Sprintf("artifact-%d", i),
+			ArtifactUrl:     fmt.Sprintf("https://example.com/artifact-%d.zip", i),
+			ArtifactVersion: "1.0.0",
+		}
+	}
+	return artifacts
+}
+
+func TestRunBatch(t *testing.T) {
+	initial, max := rateLimitInitialBackoff, rateLimitMaxBackoff
+	rateLimitInitialBackoff, rateLimitMaxBackoff = time.Millisecond, 5*time.Millisecond
+	t.Cleanup(func() { rateLimitInitialBackoff, rateLimitMaxBackoff = initial, max })
+
+	t.Run("Concurrent and ordered", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.EventsEndpoint: {Latency: platformtest.Duration(200 * time.Millisecond)},
+		}})
+		setTestEnv(t, server)
+		t.Setenv(RunnerTemp, t.TempDir())
+
+		started := time.Now()
+		results, err := (&Config{Concurrency: 8}).RunBatch(context.Background(), batchArtifacts(8))
+		assert.Nil(t, err)
+		assert.Less(t, time.Since(started), 800*time.Millisecond)
+
+		eventNames := map[string]string{}
+		for _, artifact := range server.Artifacts() {
+			eventNames[artifact.EventId] = artifact.ArtifactName
+		}
+		assert.Len(t, results, 8)
+		for i, result := range results {
+			assert.Equal(t, fmt.Sprintf("artifact-%d", i), eventNames[result.EventId])
+		}
+
+		state, err := loadState(getStatePath(&Config{}))
+		assert.Nil(t, err)
+		assert.Len(t, state.Registrations, 8)
+	})
+
+	t.Run("Shared run steps", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{
+			GithubToken: "github-token",
+			WorkflowRuns: []platformtest.WorkflowRun{
+				{Repository: "SrimanPadmanabanCB/gha-action", Id: 123456789, Attempt: 1, Status: "in_progress", StartedAt: time.Now().UTC()},
+			},
+		})
+		setTestEnv(t, server)
+		policyPath := filepath.Join(t.TempDir(), "policy.yaml")
+		assert.Nil(t, os.WriteFile(policyPath, []byte("allowed-hosts: [example.com]\n"), 0644))
+
+		config := &Config{Concurrency: 4, VerifyRun: true, GithubToken: "github-token", GithubApiUrl: server.GithubApiUrl(), PolicyPath: policyPath}
+		results, err := config.RunBatch(context.Background(), batchArtifacts(10))
+		assert.Nil(t, err)
+		assert.Len(t, results, 10)
+		assert.Equal(t, 10, server.Requests(platformtest.EventsEndpoint))
+		assert.Equal(t, 1, server.Requests(platformtest.OIDCEndpoint))
+		assert.Equal(t, 1, server.Requests(platformtest.TokenExchangeEndpoint))
+		assert.Equal(t, 1, server.Requests(platformtest.GithubEndpoint))
+	})
+
+	t.Run("Rate limited token exchange", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.TokenExchangeEndpoint: {Status: http.StatusTooManyRequests, Body: `{"code": 429, "message": "slow down"}`, Count: 2},
+		}})
+		setTestEnv(t, server)
+
+		_, err := (&Config{Concurrency: 2}).RunBatch(context.Background(), batchArtifacts(4))
+		assert.Nil(t, err)
+		assert.Equal(t, 3, server.Requests(platformtest.TokenExchangeEndpoint))
+		assert.Len(t, server.Artifacts(), 4)
+	})
+
+	t.Run("Failed token exchange", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.TokenExchangeEndpoint: {Status: http.StatusForbidden, Body: `{"code": 403, "message": "denied"}`},
+		}})
+		setTestEnv(t, server)
+
+		results, err := (&Config{Concurrency: 2}).RunBatch(context.Background(), batchArtifacts(4))
+		assert.Nil(t, results)
+		assert.ErrorContains(t, err, "denied")
+		assert.Equal(t, 1, server.Requests(platformtest.TokenExchangeEndpoint))
+		assert.Empty(t, server.Events())
+	})
+
+	t.Run("Rate limit", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+
+		started := time.Now()
+		_, err := (&Config{Concurrency: 4, RateLimit: 20}).RunBatch(context.Background(), batchArtifacts(8))
+		assert.Nil(t, err)
+		// The first 4 events use the burst, the next 4 wait for 50ms each.
+		assert.GreaterOrEqual(t, time.Since(started), 200*time.Millisecond)
+		assert.Equal(t, 8, server.Requests(platformtest.EventsEndpoint))
+	})
+
+	t.Run("Retries rate limited events", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.EventsEndpoint: {Status: http.StatusTooManyRequests, Body: `{"code": 429, "message": "slow down"}`, Count: 3},
+		}})
+		setTestEnv(t, server)
+
+		results, err := (&Config{Concurrency: 2}).RunBatch(context.Background(), batchArtifacts(4))
+		assert.Nil(t, err)
+		for _, result := range results {
+			assert.NotNil(t, result)
+		}
+		assert.Equal(t, 7, server.Requests(platformtest.EventsEndpoint))
+		assert.Len(t, server.Artifacts(), 4)
+	})
+
+	t.Run("Gives up after retries", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.EventsEndpoint: {Status: http.StatusTooManyRequests, Body: `{"code": 429, "message": "slow down"}`},
+		}})
+		setTestEnv(t, server)
+
+		results, err := (&Config{}).RunBatch(context.Background(), batchArtifacts(1))
+		assert.NotEmpty(t, results[0].Error)
+		assert.Empty(t, results[0].EventId)
+		assert.ErrorContains(t, err, "failed to register artifact-0@1.0.0: ")
+		assert.Equal(t, ExitRateLimited, ExitCode(err))
+		assert.Equal(t, MaxRateLimitRetries+1, server.Requests(platformtest.EventsEndpoint))
+	})
+
+	t.Run("Failed artifact", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+
+		artifacts := batchArtifacts(3)
+		artifacts[1].ArtifactUrl = ""
+		results, err := (&Config{Concurrency: 3}).RunBatch(context.Background(), artifacts)
+		assert.Empty(t, results[0].Error)
+		assert.Equal(t, &RegistrationResult{ArtifactName: "artifact-1", ArtifactVersion: "1.0.0", Error: "ARTIFACT_URL is not set in the environment"}, results[1])
+		assert.Empty(t, results[2].Error)
+		assert.Equal(t, "artifact-2", results[2].ArtifactName)
+		assert.ErrorContains(t, err, "failed to register artifact-1@1.0.0: ")
+	})
+
+	t.Run("Rejected access token", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.EventsEndpoint: {Status: http.StatusUnauthorized, Body: `{"code": 401, "message": "token expired"}`, Count: 1},
+		}})
+		setTestEnv(t, server)
+
+		results, err := (&Config{}).RunBatch(context.Background(), batchArtifacts(2))
+		assert.Nil(t, err)
+		assert.Empty(t, results[0].Error)
+		assert.Equal(t, 2, server.Requests(platformtest.TokenExchangeEndpoint))
+		assert.Len(t, server.Artifacts(), 2)
+	})
+}
+
+func TestSharedToken(t *testing.T) {
+	server := testserver.New(t, platformtest.Config{})
+	setTestEnv(t, server)
+	config := &Config{CloudBeesApiUrl: server.URL}
+	token := &sharedToken{}
+
+	value, err := token.get(context.Background(), config)
+	assert.Nil(t, err)
+	cached, _ := token.get(context.Background(), config)
+	assert.Equal(t, value, cached)
+	assert.Equal(t, 1, server.Requests(platformtest.TokenExchangeEndpoint))
+
+	token.expiry = time.Now().Add(tokenExpiryMargin / 2)
+	renewed, err := token.get(context.Background(), config)
+	assert.Nil(t, err)
+	assert.NotEqual(t, value, renewed)
+	assert.Equal(t, 2, server.Requests(platformtest.TokenExchangeEndpoint))
+
+	refreshed, _ := token.refresh(context.Background(), config, value)
+	assert.Equal(t, renewed, refreshed)
+	assert.Equal(t, 2, server.Requests(platformtest.TokenExchangeEndpoint))
+}
@@ -0,0 +1,88 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const toolchainProbeTimeout = 10 * time.Second
+
+// ParseToolchainProbes parses name=command probes, one per line or value,
+// e.g. go=go version.
+func ParseToolchainProbes(values ...string) (map[string]string, error) {
+	probes := map[string]string{}
+	for _, value := range values {
+		for _, line := range strings.Split(value, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			name, command, found := strings.Cut(line, "=")
+			name, command = strings.TrimSpace(name), strings.TrimSpace(command)
+			if !found || name == "" || command == "" {
+				return nil, fmt.Errorf("invalid toolchain probe %q, expected name=command", line)
+			}
+			probes[name] = command
+		}
+	}
+	return probes, nil
+}
+
+type toolchainVersions struct {
+	versions map[string]string
+}
+
+// addBuildEnvironment records the runner and the toolchain versions reported
+// by the probe commands. The probes run on the first registration of the
+// config only.
+func addBuildEnvironment(ctx context.Context, config *Config, providerInfo *ProviderInfo) {
+	providerInfo.RunnerName = os.Getenv(RunnerName)
+	providerInfo.RunnerOs = os.Getenv(RunnerOs)
+	providerInfo.RunnerArch = os.Getenv(RunnerArch)
+	providerInfo.RunnerEnvironment = os.Getenv(RunnerEnvironment)
+	providerInfo.ImageOs = os.Getenv(ImageOs)
+	providerInfo.ImageVersion = os.Getenv(ImageVersion)
+
+	if config.toolchains == nil {
+		config.toolchains = probeToolchains(ctx, config)
+	}
+	providerInfo.Toolchains = config.toolchains.versions
+}
+
+// probeToolchains runs the probe commands. A failing probe is logged and
+// left out.
+func probeToolchains(ctx context.Context, config *Config) *toolchainVersions {
+	logger := stepLogger(StepBuildEnvironment)
+	toolchains := &toolchainVersions{}
+	for name, command := range config.ToolchainProbes {
+		version, err := probeToolchain(ctx, command)
+		if err != nil {
+			logger.Warn("Toolchain probe failed", "toolchain", name, "command", command, "error", err)
+			continue
+		}
+		if toolchains.versions == nil {
+			toolchains.versions = map[string]string{}
+		}
+		toolchains.versions[name] = version
+		logger.Debug("Toolchain probed", "toolchain", name, "version", version)
+	}
+	return toolchains
+}
+
+// probeToolchain runs the command without a shell and returns the first line
+// of its output.
+func probeToolchain(ctx context.Context, command string) (string, error) {
+	args := strings.Fields(command)
+	ctx, cancel := context.WithTimeout(ctx, toolchainProbeTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		return "", err
+	}
+	version, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(version), nil
+}
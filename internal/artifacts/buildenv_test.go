@@ -0,0 +1,73 @@
+package artifacts
+
+import (
+	"context"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseToolchainProbes(t *testing.T) {
+	probes, err := ParseToolchainProbes("go=go version\n\n node = node --version \n", "java=java -version")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"go": "go version", "node": "node --version", "java": "java -version"}, probes)
+
+	_, err = ParseToolchainProbes("go version")
+	assert.Equal(t, `invalid toolchain probe "go version", expected name=command`, err.Error())
+
+	probes, err = ParseToolchainProbes()
+	assert.Nil(t, err)
+	assert.Empty(t, probes)
+}
+
+func TestBuildEnvironment(t *testing.T) {
+	server := testserver.New(t, platformtest.Config{})
+	setTestEnv(t, server)
+	t.Setenv(RunnerName, "GitHub Actions 2")
+	t.Setenv(RunnerOs, "Linux")
+	t.Setenv(RunnerArch, "X64")
+	t.Setenv(RunnerEnvironment, "github-hosted")
+	t.Setenv(ImageOs, "ubuntu24")
+	t.Setenv(ImageVersion, "20240609.1.0")
+
+	config := Config{ToolchainProbes: map[string]string{
+		"go":      "go version",
+		"missing": "gha-register-build-artifact-missing-tool --version",
+	}}
+	_, err := config.Run(context.Background())
+	assert.Nil(t, err)
+
+	var data Output
+	assert.Nil(t, server.Events()[0].DataAs(&data))
+	providerInfo := data.ProviderInfo
+	assert.Equal(t, "GitHub Actions 2", providerInfo.RunnerName)
+	assert.Equal(t, "Linux", providerInfo.RunnerOs)
+	assert.Equal(t, "X64", providerInfo.RunnerArch)
+	assert.Equal(t, "github-hosted", providerInfo.RunnerEnvironment)
+	assert.Equal(t, "ubuntu24", providerInfo.ImageOs)
+	assert.Equal(t, "20240609.1.0", providerInfo.ImageVersion)
+	assert.Len(t, providerInfo.Toolchains, 1)
+	assert.Regexp(t, `^go version go\S+ `+runtime.GOOS+"/", providerInfo.Toolchains["go"])
+
+	t.Run("Probed once", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		config := &Config{
+			ToolchainProbes: map[string]string{"go": "go version"},
+			toolchains:      &toolchainVersions{versions: map[string]string{"go": "go version cached"}},
+		}
+		_, err := config.RunBatch(context.Background(), []ArtifactInfo{
+			{ArtifactName: "a", ArtifactUrl: "docker.io/org/a:1.0.0", ArtifactVersion: "1.0.0"},
+			{ArtifactName: "b", ArtifactUrl: "docker.io/org/b:1.0.0", ArtifactVersion: "1.0.0"},
+		})
+		assert.Nil(t, err)
+		for _, event := range server.Events() {
+			var data Output
+			assert.Nil(t, event.DataAs(&data))
+			assert.Equal(t, "go version cached", data.ProviderInfo.Toolchains["go"])
+		}
+	})
+}
@@ -4,18 +4,62 @@ import "context"
 
 type Config struct {
 	context.Context
-	ArtifactName    string `json:"artifact-name,omitempty"`
-	ArtifactUrl     string `json:"artifact-url,omitempty"`
-	ArtifactVersion string `json:"artifact-version,omitempty"`
-	ArtifactType    string `json:"artifact-type,omitempty"`
-	ArtifactDigest  string `json:"artifact-digest,omitempty"`
-	ArtifactLabel   string `json:"artifact-label,omitempty"`
-	GhaRunId        string `json:"gha-run-id,omitempty"`
-	GhaRunAttempt   string `json:"gha-run-attempt,omitempty"`
-	GhaRunNumber    string `json:"gha-run-number,omitempty"`
-	CloudBeesApiUrl string `json:"cloudbees-api-url,omitempty"`
-	GhaRepository   string `json:"gha-repository,omitempty"`
-	GhaWorkflowRef  string `json:"gha-workflow-ref,omitempty"`
-	GhaServerUrl    string `json:"gha-server-url,omitempty"`
-	GhaJobName      string `json:"gha-job-name,omitempty"`
+	ArtifactName      string            `json:"artifact-name,omitempty"`
+	ArtifactUrl       string            `json:"artifact-url,omitempty"`
+	ArtifactVersion   string            `json:"artifact-version,omitempty"`
+	ArtifactType      string            `json:"artifact-type,omitempty"`
+	ArtifactDigest    string            `json:"artifact-digest,omitempty"`
+	ArtifactLabel     string            `json:"artifact-label,omitempty"`
+	GhaRunId          string            `json:"gha-run-id,omitempty"`
+	GhaRunAttempt     string            `json:"gha-run-attempt,omitempty"`
+	GhaRunNumber      string            `json:"gha-run-number,omitempty"`
+	CloudBeesApiUrl   string            `json:"cloudbees-api-url,omitempty"`
+	GhaRepository     string            `json:"gha-repository,omitempty"`
+	GhaWorkflowRef    string            `json:"gha-workflow-ref,omitempty"`
+	GhaServerUrl      string            `json:"gha-server-url,omitempty"`
+	GhaJobName        string            `json:"gha-job-name,omitempty"`
+	Provenance        bool              `json:"provenance,omitempty"`
+	ProvenancePath    string            `json:"provenance-path,omitempty"`
+	SigningKey        string            `json:"-"`
+	EventPath         string            `json:"event-path,omitempty"`
+	Infer             bool              `json:"infer,omitempty"`
+	InferDir          string            `json:"infer-dir,omitempty"`
+	Idempotent        bool              `json:"idempotent,omitempty"`
+	Force             bool              `json:"force,omitempty"`
+	StatePath         string            `json:"state-path,omitempty"`
+	VerifyRun         bool              `json:"verify-run,omitempty"`
+	GithubToken       string            `json:"-"`
+	GithubApiUrl      string            `json:"github-api-url,omitempty"`
+	OidcIssuer        string            `json:"oidc-issuer,omitempty"`
+	GhesTokenExchange bool              `json:"ghes-token-exchange,omitempty"`
+	PolicyPath        string            `json:"policy,omitempty"`
+	PolicyMode        string            `json:"policy-mode,omitempty"`
+	GitMetadata       bool              `json:"git-metadata,omitempty"`
+	ToolchainProbes   map[string]string `json:"toolchains,omitempty"`
+	Platforms         bool              `json:"platforms,omitempty"`
+	ParentDigest      string            `json:"parent-digest,omitempty"`
+	Platform          string            `json:"platform,omitempty"`
+	RegistryUsername  string            `json:"registry-username,omitempty"`
+	RegistryPassword  string            `json:"-"`
+	RelationshipsPath string            `json:"relationships-path,omitempty"`
+	Relationships     *Relationships    `json:"relationships,omitempty"`
+	FromDir           string            `json:"from-dir,omitempty"`
+	Globs             []string          `json:"globs,omitempty"`
+	FilenameTemplate  string            `json:"filename-template,omitempty"`
+	UrlTemplate       string            `json:"url-template,omitempty"`
+	ArtifactSize      int64             `json:"artifact-size,omitempty"`
+	FromRelease       bool              `json:"from-release,omitempty"`
+	ReleaseTag        string            `json:"release-tag,omitempty"`
+	Release           *ReleaseInfo      `json:"release,omitempty"`
+	Concurrency       int               `json:"concurrency,omitempty"`
+	RateLimit         float64           `json:"rate-limit,omitempty"`
+
+	// batch is set on the config of each artifact of a batch, whose empty
+	// fields are not read from the ARTIFACT_* variables.
+	batch       bool
+	limiter     *rateLimiter
+	toolchains  *toolchainVersions
+	token       *sharedToken
+	workflowRun *workflowRun
+	policy      *Policy
 }
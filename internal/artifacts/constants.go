@@ -29,4 +29,177 @@ const (
 	ActionIdTokenRequestUrl    = "ACTIONS_ID_TOKEN_REQUEST_URL"
 	ActionIdTokenRequestToken  = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
 	AccessToken                = "accessToken"
+
+	ArtifactProvenance          = "ARTIFACT_PROVENANCE"
+	ArtifactProvenancePath      = "ARTIFACT_PROVENANCE_PATH"
+	DefaultGithubServerUrl      = "https://github.com"
+	InTotoStatementType         = "https://in-toto.io/Statement/v1"
+	SlsaProvenancePredicateType = "https://slsa.dev/provenance/v1"
+	GithubWorkflowBuildType     = "https://actions.github.io/buildtypes/workflow/v1"
+
+	ArtifactSigningKey      = "ARTIFACT_SIGNING_KEY"
+	ArtifactEventPath       = "ARTIFACT_EVENT_PATH"
+	SignatureExtension      = "signature"
+	SignatureAlgExtension   = "signaturealg"
+	SignatureKeyIdExtension = "signaturekeyid"
+	SignatureAlgEdDSA       = "EdDSA"
+	SignatureAlgES256       = "ES256"
+	SignatureAlgES384       = "ES384"
+	SignatureAlgES512       = "ES512"
+
+	DockerHubRegistry = "docker.io"
+
+	ArtifactInfer    = "ARTIFACT_INFER"
+	ArtifactInferDir = "ARTIFACT_INFER_DIR"
+
+	ArtifactIdempotent   = "ARTIFACT_IDEMPOTENT"
+	ArtifactForce        = "ARTIFACT_FORCE"
+	ArtifactStatePath    = "ARTIFACT_STATE_PATH"
+	RunnerTemp           = "RUNNER_TEMP"
+	StateFileName        = "gha-register-build-artifact-state.json"
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	GithubOutput = "GITHUB_OUTPUT"
+
+	RequestIdHeaderKey   = "X-Request-Id"
+	RetryAfterHeaderKey  = "Retry-After"
+	TraceParentHeaderKey = "Traceparent"
+	LogRequestId         = "request_id"
+	LogTraceId           = "trace_id"
+
+	ArtifactVerifyRun         = "ARTIFACT_VERIFY_RUN"
+	GithubToken               = "GITHUB_TOKEN"
+	GithubApiUrl              = "GITHUB_API_URL"
+	DefaultGithubApiUrl       = "https://api.github.com"
+	GithubJsonMediaType       = "application/vnd.github+json"
+	GithubApiVersionHeaderKey = "X-GitHub-Api-Version"
+	LinkHeaderKey             = "Link"
+	GithubApiVersion          = "2022-11-28"
+	GhcrRegistry              = "ghcr.io"
+	StepVerifyRun             = "verify-run"
+
+	ArtifactOidcIssuer        = "ARTIFACT_OIDC_ISSUER"
+	DefaultOidcIssuer         = "https://token.actions.githubusercontent.com"
+	GhesTokenIssuerPath       = "/_services/token"
+	GheComDomain              = ".ghe.com"
+	GithubEnterpriseProvider  = "GITHUB_ENTERPRISE"
+	ArtifactGhesTokenExchange = "ARTIFACT_GHES_TOKEN_EXCHANGE"
+
+	ArtifactPolicy           = "ARTIFACT_POLICY"
+	ArtifactPolicyMode       = "ARTIFACT_POLICY_MODE"
+	PolicyModeEnforce        = "enforce"
+	PolicyModeAudit          = "audit"
+	VersionSchemeSemver      = "semver"
+	VersionSchemeCalver      = "calver"
+	PolicyRuleAllowedHosts   = "allowed-hosts"
+	PolicyRuleRequireDigest  = "require-digest"
+	PolicyRuleVersion        = "version"
+	PolicyRuleRequiredLabels = "required-labels"
+	PolicyRuleBranches       = "branches"
+	StepPolicy               = "policy"
+
+	GithubSha           = "GITHUB_SHA"
+	GithubRef           = "GITHUB_REF"
+	GithubHeadRef       = "GITHUB_HEAD_REF"
+	GithubEventPath     = "GITHUB_EVENT_PATH"
+	GithubActor         = "GITHUB_ACTOR"
+	GithubWorkspace     = "GITHUB_WORKSPACE"
+	ArtifactGitMetadata = "ARTIFACT_GIT_METADATA"
+	StepSource          = "source"
+
+	RunnerName           = "RUNNER_NAME"
+	RunnerOs             = "RUNNER_OS"
+	RunnerArch           = "RUNNER_ARCH"
+	RunnerEnvironment    = "RUNNER_ENVIRONMENT"
+	ImageOs              = "ImageOS"
+	ImageVersion         = "ImageVersion"
+	ArtifactToolchains   = "ARTIFACT_TOOLCHAINS"
+	StepBuildEnvironment = "build-environment"
+
+	ArtifactPlatforms            = "ARTIFACT_PLATFORMS"
+	ArtifactRegistryUsername     = "ARTIFACT_REGISTRY_USERNAME"
+	ArtifactRegistryPassword     = "ARTIFACT_REGISTRY_PASSWORD"
+	DockerHubApiHost             = "registry-1.docker.io"
+	MediaTypeOciIndex            = "application/vnd.oci.image.index.v1+json"
+	MediaTypeOciManifest         = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeDockerManifestList  = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeDockerManifest      = "application/vnd.docker.distribution.manifest.v2+json"
+	DockerContentDigestHeaderKey = "Docker-Content-Digest"
+	WwwAuthenticateHeaderKey     = "WWW-Authenticate"
+	StepPlatforms                = "platforms"
+	AttributePlatform            = "artifact.platform"
+
+	ArtifactRelationships = "ARTIFACT_RELATIONSHIPS"
+	ArtifactContains      = "ARTIFACT_CONTAINS"
+	ArtifactBuiltFrom     = "ARTIFACT_BUILT_FROM"
+	ArtifactDependsOn     = "ARTIFACT_DEPENDS_ON"
+	RelationshipContains  = "contains"
+	RelationshipBuiltFrom = "built-from"
+	RelationshipDependsOn = "depends-on"
+	StepRelationships     = "relationships"
+
+	ArtifactFromDir          = "ARTIFACT_FROM_DIR"
+	ArtifactGlob             = "ARTIFACT_GLOB"
+	ArtifactFilenameTemplate = "ARTIFACT_FILENAME_TEMPLATE"
+	ArtifactUrlTemplate      = "ARTIFACT_URL_TEMPLATE"
+	DefaultFilenameTemplate  = "{name}-{version}.{ext}"
+	StepScan                 = "scan"
+
+	ArtifactFromRelease  = "ARTIFACT_FROM_RELEASE"
+	ArtifactReleaseTag   = "ARTIFACT_RELEASE_TAG"
+	OctetStreamMediaType = "application/octet-stream"
+	TagRefPrefix         = "refs/tags/"
+	StepRelease          = "release"
+
+	ArtifactConcurrency = "ARTIFACT_CONCURRENCY"
+	ArtifactRateLimit   = "ARTIFACT_RATE_LIMIT"
+	DefaultConcurrency  = 4
+	DefaultRateLimit    = 10
+	MaxRateLimitRetries = 5
+	StepBatch           = "batch"
+
+	CheckPass              = "pass"
+	CheckFail              = "fail"
+	CheckWarn              = "warn"
+	CheckSkip              = "skip"
+	CheckEnvironment       = "environment"
+	CheckIdTokenPermission = "id-token-permission"
+	CheckApiUrl            = "cloudbees-url"
+	CheckProxy             = "proxy"
+	CheckDns               = "dns"
+	CheckTls               = "tls"
+	CheckOidcToken         = "oidc-token"
+	CheckJwtClaims         = "jwt-claims"
+	CheckTokenExchange     = "token-exchange"
+	StepDoctor             = "doctor"
+
+	ExecCredentialApiVersion = "client.authentication.k8s.io/v1"
+	ExecCredentialKind       = "ExecCredential"
+
+	RunnerDebug       = "RUNNER_DEBUG"
+	LogFormat         = "LOG_FORMAT"
+	LogLevel          = "LOG_LEVEL"
+	LogFormatText     = "text"
+	LogFormatJson     = "json"
+	LogStep           = "step"
+	LogEventId        = "event_id"
+	LogHttpStatus     = "http_status"
+	StepInfer         = "infer"
+	StepValidate      = "validate"
+	StepProvenance    = "provenance"
+	StepOidc          = "oidc"
+	StepTokenExchange = "token-exchange"
+	StepSendEvent     = "send-event"
+	AcceptHeaderKey   = "Accept"
+
+	OtelExporterOtlpEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	OtlpTracesPath           = "/v1/traces"
+	TracerName               = "gha-register-build-artifact"
+	SpanRegister             = "register-artifact"
+	TraceParentExtension     = "traceparent"
+	TraceStateExtension      = "tracestate"
+	AttributeEventId         = "cloudevents.event_id"
+	AttributeArtifactName    = "artifact.name"
+	AttributeArtifactVersion = "artifact.version"
+	AttributeHttpStatus      = "http.response.status_code"
 )
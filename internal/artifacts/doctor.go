@@ -0,0 +1,232 @@
+package artifacts
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const doctorTimeout = 10 * time.Second
+
+// Check is the outcome of a doctor check.
+type Check struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// DoctorReport lists the doctor checks in the order they ran.
+type DoctorReport struct {
+	Checks []Check `json:"checks"`
+}
+
+// Failed returns the number of failed checks.
+func (report *DoctorReport) Failed() int {
+	failed := 0
+	for _, check := range report.Checks {
+		if check.Status == CheckFail {
+			failed++
+		}
+	}
+	return failed
+}
+
+func (report *DoctorReport) add(name, status, message, hint string) {
+	report.Checks = append(report.Checks, Check{Name: name, Status: status, Message: message, Hint: hint})
+	logger := stepLogger(StepDoctor).With("check", name, "status", status)
+	if status == CheckFail {
+		logger.Debug(message, "hint", hint)
+	} else {
+		logger.Debug(message)
+	}
+}
+
+// Doctor diagnoses the environment of a registration without sending an
+// event: the workflow variables, the id-token permission, the reachability
+// of the CloudBees API, the OIDC token claims and the token exchange, which
+// fails when the organization does not grant the repository access. Checks
+// depending on a failed check are skipped.
+func (config *Config) Doctor(ctx context.Context) *DoctorReport {
+	report := &DoctorReport{}
+	loadServerUrl(config)
+
+	var missing []string
+	for _, env := range []string{GithubRunId, GithubRunAttempt, GithubRunNumber, GithubRepository, GithubWorkflowRef, GithubJobName, CloudbeesApiUrl} {
+		if os.Getenv(env) == "" {
+			missing = append(missing, env)
+		}
+	}
+	if len(missing) > 0 {
+		report.add(CheckEnvironment, CheckFail, strings.Join(missing, ", ")+" not set", "run the doctor in a GitHub Actions job and set the cloudbees-url input")
+	} else {
+		report.add(CheckEnvironment, CheckPass, "GitHub Actions variables and "+CloudbeesApiUrl+" are set", "")
+	}
+
+	idTokenReady := os.Getenv(ActionIdTokenRequestUrl) != "" && os.Getenv(ActionIdTokenRequestToken) != ""
+	if idTokenReady {
+		report.add(CheckIdTokenPermission, CheckPass, "the job can request OIDC tokens", "")
+	} else {
+		report.add(CheckIdTokenPermission, CheckFail, ActionIdTokenRequestUrl+" or "+ActionIdTokenRequestToken+" not set", "grant `permissions: id-token: write` to the workflow or job")
+	}
+
+	config.CloudBeesApiUrl = firstNonEmpty(config.CloudBeesApiUrl, os.Getenv(CloudbeesApiUrl))
+	apiUrl, reachable := checkApiUrl(ctx, report, config.CloudBeesApiUrl)
+
+	if !idTokenReady {
+		report.add(CheckOidcToken, CheckSkip, "the job cannot request OIDC tokens", "")
+		return skipAfterOidc(report)
+	}
+	oidcToken, err := getOIDCToken(ctx, config.CloudBeesApiUrl)
+	if err != nil {
+		report.add(CheckOidcToken, CheckFail, "failed to fetch the OIDC token: "+err.Error(), "check that the job has `id-token: write` and that the runner can reach "+os.Getenv(ActionIdTokenRequestUrl))
+		return skipAfterOidc(report)
+	}
+	report.add(CheckOidcToken, CheckPass, "OIDC token fetched", "")
+
+	if !checkClaims(report, config, oidcToken, apiUrl) || !reachable {
+		report.add(CheckTokenExchange, CheckSkip, "the OIDC token or the CloudBees API is not usable", "")
+		return report
+	}
+
+	_, err = exchangeToken(ctx, config, oidcToken)
+	if err != nil {
+		report.add(CheckTokenExchange, CheckFail, err.Error(), tokenExchangeHint(err))
+		return report
+	}
+	report.add(CheckTokenExchange, CheckPass, "OIDC token exchanged for a CloudBees token", "")
+	return report
+}
+
+func checkApiUrl(ctx context.Context, report *DoctorReport, rawUrl string) (*url.URL, bool) {
+	apiUrl, err := url.Parse(rawUrl)
+	if rawUrl == "" || err != nil || apiUrl.Host == "" || (apiUrl.Scheme != "https" && apiUrl.Scheme != "http") {
+		report.add(CheckApiUrl, CheckFail, fmt.Sprintf("invalid CloudBees API URL %q", rawUrl), "set cloudbees-url to the API URL, e.g. https://api.cloudbees.io")
+		for _, name := range []string{CheckProxy, CheckDns, CheckTls} {
+			report.add(name, CheckSkip, "invalid CloudBees API URL", "")
+		}
+		return nil, false
+	}
+	if apiUrl.Scheme == "http" && !isLoopback(apiUrl.Hostname()) {
+		report.add(CheckApiUrl, CheckWarn, rawUrl+" is not using https", "use the https URL of the CloudBees API")
+	} else {
+		report.add(CheckApiUrl, CheckPass, rawUrl, "")
+	}
+
+	proxyUrl, err := http.ProxyFromEnvironment(&http.Request{URL: apiUrl})
+	switch {
+	case err != nil:
+		report.add(CheckProxy, CheckFail, "invalid proxy configuration: "+err.Error(), "fix HTTPS_PROXY, HTTP_PROXY and NO_PROXY")
+		return apiUrl, false
+	case proxyUrl != nil:
+		report.add(CheckProxy, CheckPass, "connecting through proxy "+proxyUrl.Redacted(), "")
+	default:
+		report.add(CheckProxy, CheckPass, "connecting directly", "")
+	}
+
+	dnsHost := apiUrl.Hostname()
+	if proxyUrl != nil {
+		dnsHost = proxyUrl.Hostname()
+	}
+	dnsCtx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	addresses, err := net.DefaultResolver.LookupHost(dnsCtx, dnsHost)
+	if err != nil {
+		report.add(CheckDns, CheckFail, "failed to resolve "+dnsHost+": "+err.Error(), "check the host of cloudbees-url and the DNS or proxy settings of the runner")
+		report.add(CheckTls, CheckSkip, dnsHost+" does not resolve", "")
+		return apiUrl, false
+	}
+	report.add(CheckDns, CheckPass, dnsHost+" resolves to "+strings.Join(addresses, ", "), "")
+
+	if apiUrl.Scheme != "https" {
+		report.add(CheckTls, CheckSkip, "the CloudBees API URL is not using https", "")
+		return apiUrl, true
+	}
+	if proxyUrl != nil {
+		report.add(CheckTls, CheckSkip, "TLS is negotiated through the proxy when sending requests", "")
+		return apiUrl, true
+	}
+	port := apiUrl.Port()
+	if port == "" {
+		port = "443"
+	}
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: doctorTimeout}}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(apiUrl.Hostname(), port))
+	if err != nil {
+		report.add(CheckTls, CheckFail, "TLS connection to "+apiUrl.Host+" failed: "+err.Error(), "a proxy or firewall may intercept TLS; install its CA certificate on the runner or configure HTTPS_PROXY")
+		return apiUrl, false
+	}
+	defer conn.Close()
+	state := conn.(*tls.Conn).ConnectionState()
+	certificate := state.PeerCertificates[0]
+	message := fmt.Sprintf("%s, certificate for %s valid until %s", tls.VersionName(state.Version), certificate.Subject.CommonName, certificate.NotAfter.Format(time.DateOnly))
+	if time.Until(certificate.NotAfter) < 14*24*time.Hour {
+		report.add(CheckTls, CheckWarn, message, "the certificate of the CloudBees API expires soon")
+	} else {
+		report.add(CheckTls, CheckPass, message, "")
+	}
+	return apiUrl, true
+}
+
+func checkClaims(report *DoctorReport, config *Config, token string, apiUrl *url.URL) bool {
+	claims, err := decodeClaims(token)
+	if err != nil {
+		report.add(CheckJwtClaims, CheckFail, err.Error(), "")
+		return false
+	}
+	summary := fmt.Sprintf("sub=%s, aud=%s, repository=%s, ref=%s", claims.Subject, strings.Join(claims.Audience, ","), claims.Repository, claims.Ref)
+	if err := validateTokenIssuer(config, token); err != nil {
+		report.add(CheckJwtClaims, CheckFail, err.Error(), "set oidc-issuer to the issuer of your GitHub server")
+		return false
+	}
+	expectedAudience := strings.TrimSuffix(config.CloudBeesApiUrl, "/")
+	if apiUrl != nil && !slices.Contains(claims.Audience, expectedAudience) {
+		report.add(CheckJwtClaims, CheckFail, fmt.Sprintf("audience %s does not contain %s", strings.Join(claims.Audience, ","), expectedAudience), "")
+		return false
+	}
+	if claims.Expiry != 0 && time.Unix(claims.Expiry, 0).Before(time.Now()) {
+		report.add(CheckJwtClaims, CheckFail, "the OIDC token has expired, "+summary, "check the clock of the runner")
+		return false
+	}
+	report.add(CheckJwtClaims, CheckPass, "iss="+claims.Issuer+", "+summary, "")
+	return true
+}
+
+func skipAfterOidc(report *DoctorReport) *DoctorReport {
+	for _, name := range []string{CheckJwtClaims, CheckTokenExchange} {
+		report.add(name, CheckSkip, "no OIDC token", "")
+	}
+	return report
+}
+
+func tokenExchangeHint(err error) string {
+	var platformErr *PlatformError
+	if !errors.As(err, &platformErr) {
+		return "check that the runner can reach the CloudBees API"
+	}
+	switch platformErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "check that the CloudBees organization trusts GitHub OIDC tokens of this repository and grants it access"
+	case http.StatusNotFound:
+		return "check that cloudbees-url is the CloudBees API URL, not the UI URL"
+	case http.StatusTooManyRequests:
+		return "the platform is rate limiting requests, retry later"
+	}
+	return ""
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
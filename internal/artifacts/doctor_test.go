@@ -0,0 +1,109 @@
+package artifacts
+
+import (
+	"context"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func checkStatuses(report *DoctorReport) map[string]string {
+	statuses := map[string]string{}
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	return statuses
+}
+
+func TestDoctor(t *testing.T) {
+
+	t.Run("Healthy", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		t.Setenv(ActionIdTokenRequestToken, "request-token")
+
+		report := (&Config{}).Doctor(context.Background())
+		assert.Equal(t, 0, report.Failed())
+		assert.Equal(t, map[string]string{
+			CheckEnvironment:       CheckPass,
+			CheckIdTokenPermission: CheckPass,
+			CheckApiUrl:            CheckPass,
+			CheckProxy:             CheckPass,
+			CheckDns:               CheckPass,
+			CheckTls:               CheckSkip,
+			CheckOidcToken:         CheckPass,
+			CheckJwtClaims:         CheckPass,
+			CheckTokenExchange:     CheckPass,
+		}, checkStatuses(report))
+		assert.Empty(t, server.Events())
+	})
+
+	t.Run("Missing id-token permission", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		t.Setenv(ActionIdTokenRequestUrl, "")
+
+		report := (&Config{}).Doctor(context.Background())
+		assert.Equal(t, 1, report.Failed())
+		statuses := checkStatuses(report)
+		assert.Equal(t, CheckFail, statuses[CheckIdTokenPermission])
+		assert.Equal(t, CheckSkip, statuses[CheckOidcToken])
+		assert.Equal(t, CheckSkip, statuses[CheckTokenExchange])
+		assert.Equal(t, "grant `permissions: id-token: write` to the workflow or job", report.Checks[1].Hint)
+		assert.Zero(t, server.Requests(platformtest.TokenExchangeEndpoint))
+	})
+
+	t.Run("Missing environment", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		t.Setenv(ActionIdTokenRequestToken, "request-token")
+		t.Setenv(GithubRunId, "")
+
+		report := (&Config{}).Doctor(context.Background())
+		assert.Equal(t, 1, report.Failed())
+		assert.Equal(t, GithubRunId+" not set", report.Checks[0].Message)
+		statuses := checkStatuses(report)
+		assert.Equal(t, CheckPass, statuses[CheckTokenExchange])
+	})
+
+	t.Run("Invalid url", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		t.Setenv(ActionIdTokenRequestToken, "request-token")
+
+		report := (&Config{CloudBeesApiUrl: "api.cloudbees.io"}).Doctor(context.Background())
+		statuses := checkStatuses(report)
+		assert.Equal(t, CheckFail, statuses[CheckApiUrl])
+		assert.Equal(t, CheckSkip, statuses[CheckDns])
+		assert.Equal(t, CheckSkip, statuses[CheckTokenExchange])
+	})
+
+	t.Run("Wrong issuer", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		t.Setenv(ActionIdTokenRequestToken, "request-token")
+
+		report := (&Config{OidcIssuer: "https://issuer.example.com"}).Doctor(context.Background())
+		statuses := checkStatuses(report)
+		assert.Equal(t, CheckFail, statuses[CheckJwtClaims])
+		assert.Equal(t, CheckSkip, statuses[CheckTokenExchange])
+	})
+
+	t.Run("Token exchange denied", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.TokenExchangeEndpoint: {Status: http.StatusForbidden, Body: `{"code": 403, "message": "organization does not trust the repository"}`},
+		}})
+		setTestEnv(t, server)
+		t.Setenv(ActionIdTokenRequestToken, "request-token")
+
+		report := (&Config{}).Doctor(context.Background())
+		assert.Equal(t, 1, report.Failed())
+		check := report.Checks[8]
+		assert.Equal(t, CheckTokenExchange, check.Name)
+		assert.Contains(t, check.Message, "organization does not trust the repository")
+		assert.Equal(t, "check that the CloudBees organization trusts GitHub OIDC tokens of this repository and grants it access", check.Hint)
+	})
+}
@@ -0,0 +1,198 @@
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exit codes of the command, so that workflows can tell failures apart.
+const (
+	ExitFailure             = 1
+	ExitInvalidRequest      = 2
+	ExitUnauthorized        = 3
+	ExitRateLimited         = 4
+	ExitPlatformUnavailable = 5
+	ExitPolicyViolation     = 6
+)
+
+// PlatformError is an error response of the CloudBees platform.
+type PlatformError struct {
+	// Operation is the failed call, e.g. "error sending CloudEvent to platform".
+	Operation string `json:"operation"`
+	// StatusCode and Status are the HTTP status of the response.
+	StatusCode int    `json:"status_code"`
+	Status     string `json:"status"`
+	// Code and Message are decoded from the error response body. Message falls
+	// back to the raw body when it is not a JSON error.
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	// FieldViolations, QuotaViolations and RetryDelay are decoded from the
+	// known error details.
+	FieldViolations []FieldViolation `json:"field_violations,omitempty"`
+	QuotaViolations []QuotaViolation `json:"quota_violations,omitempty"`
+	RetryDelay      time.Duration    `json:"retry_delay,omitempty"`
+	// Details are the error details of unknown types.
+	Details []any `json:"details,omitempty"`
+	// RequestId and TraceId correlate the error with the platform logs.
+	RequestId string `json:"request_id,omitempty"`
+	TraceId   string `json:"trace_id,omitempty"`
+}
+
+// FieldViolation is a google.rpc.BadRequest detail.
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// QuotaViolation is a google.rpc.QuotaFailure detail.
+type QuotaViolation struct {
+	Subject     string `json:"subject"`
+	Description string `json:"description"`
+}
+
+type errorDetail struct {
+	Type            string           `json:"@type"`
+	FieldViolations []FieldViolation `json:"fieldViolations"`
+	Violations      []QuotaViolation `json:"violations"`
+	RetryDelay      string           `json:"retryDelay"`
+}
+
+func newPlatformError(ctx context.Context, operation string, resp *http.Response, body []byte) *PlatformError {
+	platformErr := &PlatformError{
+		Operation:  operation,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Message:    string(body),
+		RequestId:  resp.Header.Get(RequestIdHeaderKey),
+		TraceId:    traceId(ctx, resp),
+	}
+	if retryAfter, err := strconv.Atoi(resp.Header.Get(RetryAfterHeaderKey)); err == nil {
+		platformErr.RetryDelay = time.Duration(retryAfter) * time.Second
+	}
+
+	errorResponse := ErrorResponse{}
+	if err := json.Unmarshal(body, &errorResponse); err != nil || (errorResponse.Message == "" && len(errorResponse.Details) == 0) {
+		return platformErr
+	}
+	platformErr.Code = errorResponse.Code
+	if errorResponse.Message != "" {
+		platformErr.Message = errorResponse.Message
+	}
+	for _, detail := range errorResponse.Details {
+		if !platformErr.decodeDetail(detail) {
+			platformErr.Details = append(platformErr.Details, detail)
+		}
+	}
+	return platformErr
+}
+
+func (e *PlatformError) decodeDetail(value any) bool {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	var detail errorDetail
+	if err := json.Unmarshal(data, &detail); err != nil {
+		return false
+	}
+	switch detail.Type[strings.LastIndex(detail.Type, "/")+1:] {
+	case "google.rpc.BadRequest":
+		e.FieldViolations = append(e.FieldViolations, detail.FieldViolations...)
+	case "google.rpc.QuotaFailure":
+		e.QuotaViolations = append(e.QuotaViolations, detail.Violations...)
+	case "google.rpc.RetryInfo":
+		delay, err := time.ParseDuration(detail.RetryDelay)
+		if err != nil {
+			return false
+		}
+		e.RetryDelay = delay
+	default:
+		return false
+	}
+	return true
+}
+
+// traceId returns the trace ID of the platform response, or of the request
+// when the platform did not return one.
+func traceId(ctx context.Context, resp *http.Response) string {
+	if traceParent := strings.Split(resp.Header.Get(TraceParentHeaderKey), "-"); len(traceParent) == 4 {
+		return traceParent[1]
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		return spanContext.TraceID().String()
+	}
+	return ""
+}
+
+func (e *PlatformError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s - %s : %s", e.Operation, e.Status, e.Message)
+	for _, violation := range e.FieldViolations {
+		fmt.Fprintf(&sb, "\n  invalid field %s: %s", violation.Field, violation.Description)
+	}
+	for _, violation := range e.QuotaViolations {
+		fmt.Fprintf(&sb, "\n  quota exceeded for %s: %s", violation.Subject, violation.Description)
+	}
+	if e.RetryDelay > 0 {
+		fmt.Fprintf(&sb, "\n  retry after %s", e.RetryDelay)
+	}
+	if e.RequestId != "" {
+		fmt.Fprintf(&sb, "\n  request id: %s", e.RequestId)
+	}
+	if e.TraceId != "" {
+		fmt.Fprintf(&sb, "\n  trace id: %s", e.TraceId)
+	}
+	return sb.String()
+}
+
+// LogAttrs returns the correlation attributes of the error for the log.
+func (e *PlatformError) LogAttrs() []any {
+	attrs := []any{LogHttpStatus, e.StatusCode}
+	if e.RequestId != "" {
+		attrs = append(attrs, LogRequestId, e.RequestId)
+	}
+	if e.TraceId != "" {
+		attrs = append(attrs, LogTraceId, e.TraceId)
+	}
+	return attrs
+}
+
+// ExitCode maps an error to the exit code of the command.
+func ExitCode(err error) int {
+	var policyErr *PolicyError
+	if errors.As(err, &policyErr) {
+		return ExitPolicyViolation
+	}
+	var platformErr *PlatformError
+	if !errors.As(err, &platformErr) {
+		return ExitFailure
+	}
+	switch {
+	case platformErr.StatusCode == http.StatusUnauthorized || platformErr.StatusCode == http.StatusForbidden:
+		return ExitUnauthorized
+	case platformErr.StatusCode == http.StatusTooManyRequests || len(platformErr.QuotaViolations) > 0:
+		return ExitRateLimited
+	case platformErr.StatusCode >= 500:
+		return ExitPlatformUnavailable
+	case platformErr.StatusCode >= 400:
+		return ExitInvalidRequest
+	}
+	return ExitFailure
+}
+
+// ErrorLogAttrs returns the log attributes of an error returned by the command.
+func ErrorLogAttrs(err error) []any {
+	var platformErr *PlatformError
+	if errors.As(err, &platformErr) {
+		return platformErr.LogAttrs()
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatformError(t *testing.T) {
+
+	t.Run("Details", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.EventsEndpoint: {Status: http.StatusBadRequest, Body: `{
+				"code": 3,
+				"message": "invalid artifact",
+				"details": [
+					{"@type": "type.googleapis.com/google.rpc.BadRequest", "fieldViolations": [{"field": "artifact_url", "description": "must not be empty"}]},
+					{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "30s"},
+					{"@type": "type.googleapis.com/google.rpc.DebugInfo", "detail": "validation"}
+				]}`},
+		}})
+		setTestEnv(t, server)
+		_, err := (&Config{}).Run(context.Background())
+
+		var platformErr *PlatformError
+		assert.True(t, errors.As(err, &platformErr))
+		assert.Equal(t, http.StatusBadRequest, platformErr.StatusCode)
+		assert.Equal(t, 3, platformErr.Code)
+		assert.Equal(t, []FieldViolation{{Field: "artifact_url", Description: "must not be empty"}}, platformErr.FieldViolations)
+		assert.Equal(t, 30*time.Second, platformErr.RetryDelay)
+		assert.Len(t, platformErr.Details, 1)
+		assert.NotEmpty(t, platformErr.RequestId)
+		assert.Equal(t, "error sending CloudEvent to platform - 400 Bad Request : invalid artifact\n"+
+			"  invalid field artifact_url: must not be empty\n"+
+			"  retry after 30s\n"+
+			"  request id: "+platformErr.RequestId, err.Error())
+		assert.Equal(t, ExitInvalidRequest, ExitCode(err))
+		assert.Equal(t, []any{LogHttpStatus, http.StatusBadRequest, LogRequestId, platformErr.RequestId}, ErrorLogAttrs(err))
+	})
+
+	t.Run("Quota and trace", func(t *testing.T) {
+		resp := &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Status:     "429 Too Many Requests",
+			Header: http.Header{
+				RetryAfterHeaderKey:  {"10"},
+				TraceParentHeaderKey: {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			},
+		}
+		body := []byte(`{"message": "quota exceeded", "details": [{"@type": "google.rpc.QuotaFailure", "violations": [{"subject": "org:acme", "description": "1000 events per hour"}]}]}`)
+		err := newPlatformError(context.Background(), "error during token exchange", resp, body)
+		assert.Equal(t, "error during token exchange - 429 Too Many Requests : quota exceeded\n"+
+			"  quota exceeded for org:acme: 1000 events per hour\n"+
+			"  retry after 10s\n"+
+			"  trace id: 4bf92f3577b34da6a3ce929d0e0e4736", err.Error())
+		assert.Equal(t, ExitRateLimited, ExitCode(fmt.Errorf("wrapped: %w", err)))
+	})
+
+	t.Run("Raw body", func(t *testing.T) {
+		resp := &http.Response{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway", Header: http.Header{}}
+		err := newPlatformError(context.Background(), "error querying artifacts", resp, []byte("upstream unavailable"))
+		assert.Equal(t, "error querying artifacts - 502 Bad Gateway : upstream unavailable", err.Error())
+		assert.Equal(t, ExitPlatformUnavailable, ExitCode(err))
+	})
+
+	t.Run("Other errors", func(t *testing.T) {
+		err := errors.New("ARTIFACT_NAME is not set in the environment")
+		assert.Equal(t, ExitFailure, ExitCode(err))
+		assert.Nil(t, ErrorLogAttrs(err))
+	})
+}
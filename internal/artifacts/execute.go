@@ -3,12 +3,11 @@ package artifacts
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
@@ -16,7 +15,8 @@ import (
 	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
-	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ErrorResponse struct {
@@ -26,27 +26,157 @@ type ErrorResponse struct {
 }
 
 type TokenRequest struct {
-	Provider string `json:"provider"`
-	Audience string `json:"audience"`
+	Provider  string `json:"provider"`
+	Audience  string `json:"audience"`
+	ServerUrl string `json:"serverUrl,omitempty"`
+	Issuer    string `json:"issuer,omitempty"`
 }
 
-func (config *Config) Run(_ context.Context) (err error) {
+// Run registers the artifact on the platform and returns the registration.
+func (config *Config) Run(ctx context.Context) (result *RegistrationResult, err error) {
+	ctx, span := startSpan(ctx, SpanRegister)
+	defer func() { endSpan(span, err) }()
 
-	validationError := setEnvVars(config)
-	if validationError != nil {
-		return validationError
+	if config.Infer {
+		inferred, err := inferArtifactInfo(config)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range inferred {
+			stepLogger(StepInfer).Info("Inferred artifact field", "field", value.Field, "value", value.Value, "source", value.Source)
+		}
+	}
+
+	err = validate(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.RelationshipsPath != "" || config.Relationships != nil {
+		err = checkRelationships(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.PolicyPath != "" {
+		err = checkPolicy(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.VerifyRun {
+		err = verifyWorkflowRun(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Platforms {
+		return registerPlatforms(ctx, config)
+	}
+	return register(ctx, config)
+}
+
+// register sends the registration event of a validated artifact, unless it
+// was already registered in this job.
+func register(ctx context.Context, config *Config) (result *RegistrationResult, err error) {
+	entry, registered, err := registeredEvent(config)
+	if err != nil {
+		return nil, err
+	}
+	if registered && !config.Force {
+		stepLogger(StepValidate).Info("Artifact already registered in this job, skipping", LogEventId, entry.EventId, "name", config.ArtifactName, "version", config.ArtifactVersion)
+		return &RegistrationResult{EventId: entry.EventId, RegistrationId: entry.RegistrationId, Platform: config.Platform, Skipped: true}, nil
 	}
 
 	cloudEventData := prepareCloudEventData(config)
+	cloudEventData.SourceInfo = getSourceInfo(config)
+	cloudEventData.Relationships = config.Relationships
+	cloudEventData.Release = config.Release
+	addBuildEnvironment(ctx, config, &cloudEventData.ProviderInfo)
+
+	if config.Provenance {
+		err = attachProvenance(config, &cloudEventData)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	cloudEvent, err := prepareCloudEvent(config, cloudEventData)
+	if err != nil {
+		return nil, err
+	}
+	setTraceExtension(ctx, &cloudEvent)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String(AttributeEventId, cloudEvent.ID()))
+
+	if config.SigningKey != "" {
+		signer, err := LoadSigningKey(config.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+		err = signCloudEvent(&cloudEvent, signer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.EventPath != "" {
+		err = writeCloudEvent(cloudEvent, config.EventPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var accessToken string
+	if config.token != nil {
+		accessToken, err = config.token.get(ctx, config)
+	} else {
+		accessToken, err = authenticate(ctx, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result, err = sendCloudEvent(ctx, cloudEvent, config, accessToken)
+	var platformErr *PlatformError
+	if config.token != nil && errors.As(err, &platformErr) && platformErr.StatusCode == http.StatusUnauthorized {
+		stepLogger(StepSendEvent).Warn("Access token rejected by the platform, authenticating again", LogEventId, cloudEvent.ID())
+		accessToken, err = config.token.refresh(ctx, config, accessToken)
+		if err != nil {
+			return nil, err
+		}
+		result, err = sendCloudEvent(ctx, cloudEvent, config, accessToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordRegistration(config, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func validate(ctx context.Context, config *Config) (err error) {
+	_, span := startSpan(ctx, StepValidate)
+	defer func() { endSpan(span, err) }()
+
+	err = setEnvVars(config)
 	if err != nil {
 		return err
 	}
-	err = sendCloudEvent(cloudEvent, config)
+
+	err = validateArtifactUrl(config)
 	if err != nil {
 		return err
 	}
+	span.SetAttributes(
+		attribute.String(AttributeArtifactName, config.ArtifactName),
+		attribute.String(AttributeArtifactVersion, config.ArtifactVersion),
+	)
+	stepLogger(StepValidate).Debug("Artifact validated", "name", config.ArtifactName, "version", config.ArtifactVersion, "url", config.ArtifactUrl)
 	return nil
 }
 
@@ -69,19 +199,19 @@ func setEnvVars(cfg *Config) error {
 	}
 	cfg.CloudBeesApiUrl = cloudBeesApiUrl
 
-	artifactName := os.Getenv(ArtifactName)
+	artifactName := artifactEnv(cfg, ArtifactName, cfg.ArtifactName)
 	if artifactName == "" {
 		return fmt.Errorf(ArtifactName + " is not set in the environment")
 	}
 	cfg.ArtifactName = artifactName
 
-	artifactUrl := os.Getenv(ArtifactUrl)
+	artifactUrl := artifactEnv(cfg, ArtifactUrl, cfg.ArtifactUrl)
 	if artifactUrl == "" {
 		return fmt.Errorf(ArtifactUrl + " is not set in the environment")
 	}
 	cfg.ArtifactUrl = artifactUrl
 
-	artifactVersion := os.Getenv(ArtifactVersion)
+	artifactVersion := artifactEnv(cfg, ArtifactVersion, cfg.ArtifactVersion)
 	if artifactVersion == "" {
 		return fmt.Errorf(ArtifactVersion + " is not set in the environment")
 	}
@@ -123,6 +253,15 @@ func setEnvVars(cfg *Config) error {
 	return nil
 }
 
+// artifactEnv returns the config value, or else the ARTIFACT_* variable unless
+// the artifact is part of a batch.
+func artifactEnv(cfg *Config, key string, value string) string {
+	if cfg.batch {
+		return value
+	}
+	return firstNonEmpty(value, os.Getenv(key))
+}
+
 func getExternalEventlUrl(config *Config) string {
 	if !strings.HasSuffix(config.CloudBeesApiUrl, "/") {
 		config.CloudBeesApiUrl += "/"
@@ -151,7 +290,7 @@ func getSource(config *Config) string {
 
 func prepareCloudEvent(config *Config, output Output) (cloudevents.Event, error) {
 	cloudEvent := cloudevents.NewEvent()
-	cloudEvent.SetID(uuid.NewString())
+	cloudEvent.SetID(getEventId(config))
 	cloudEvent.SetSubject(getSubject(config))
 	cloudEvent.SetType(BuildArtifactType)
 	cloudEvent.SetSource(getSource(config))
@@ -173,6 +312,9 @@ func prepareCloudEventData(config *Config) Output {
 		ArtifactType:    config.ArtifactType,
 		ArtifactDigest:  config.ArtifactDigest,
 		ArtifactLabel:   config.ArtifactLabel,
+		ArtifactSize:    config.ArtifactSize,
+		ParentDigest:    config.ParentDigest,
+		Platform:        config.Platform,
 	}
 
 	providerInfo := &ProviderInfo{
@@ -189,161 +331,244 @@ func prepareCloudEventData(config *Config) Output {
 	return output
 }
 
-func sendCloudEvent(cloudEvent cloudevents.Event, config *Config) error {
+func sendCloudEvent(ctx context.Context, cloudEvent cloudevents.Event, config *Config, accessToken string) (*RegistrationResult, error) {
+	if config.limiter == nil {
+		return postCloudEvent(ctx, config, cloudEvent, accessToken)
+	}
+
+	// A batch shares the rate limiter and retries rate limited events with the
+	// same event ID, which the platform deduplicates.
+	backoff := rateLimitInitialBackoff
+	for attempt := 1; ; attempt++ {
+		if err := config.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+		result, err := postCloudEvent(ctx, config, cloudEvent, accessToken)
+		if err == nil {
+			config.limiter.speedUp()
+			return result, nil
+		}
+		var platformErr *PlatformError
+		if !errors.As(err, &platformErr) || platformErr.StatusCode != http.StatusTooManyRequests || attempt > MaxRateLimitRetries {
+			return nil, err
+		}
+		delay := platformErr.RetryDelay
+		if delay <= 0 {
+			delay = backoff
+			backoff = min(2*backoff, rateLimitMaxBackoff)
+		}
+		paused := config.limiter.slowDown(delay)
+		stepLogger(StepSendEvent).Warn("Rate limited by the platform, slowing down", LogEventId, cloudEvent.ID(), "attempt", attempt, "retry_in", paused, "rate", config.limiter.currentRate())
+	}
+}
+
+// authenticate exchanges the GitHub OIDC token for a CloudBees platform token,
+// retrying the exchange when the platform responds with 429.
+func authenticate(ctx context.Context, config *Config) (string, error) {
 	// Fetch the OIDC token
 	// This token is used to authenticate the request to the CloudBees API
-	fmt.Println("Started fetching OIDC Token...")
-	oidcToken, err := getOIDCToken(config.CloudBeesApiUrl)
+	stepLogger(StepOidc).Info("Started fetching OIDC Token")
+	oidcToken, err := getOIDCToken(ctx, config.CloudBeesApiUrl)
 	if err != nil {
-		return fmt.Errorf("failed to create oidc token - %s", err.Error())
+		return "", fmt.Errorf("failed to create oidc token - %s", err.Error())
+	}
+	if err := validateTokenIssuer(config, oidcToken); err != nil {
+		return "", err
 	}
-	fmt.Println("OIDC Token fetched successfully!")
+	stepLogger(StepOidc).Info("OIDC Token fetched successfully")
+
+	backoff := rateLimitInitialBackoff
+	for attempt := 1; ; attempt++ {
+		accessToken, err := exchangeToken(ctx, config, oidcToken)
+		var platformErr *PlatformError
+		if err == nil || !errors.As(err, &platformErr) || platformErr.StatusCode != http.StatusTooManyRequests || attempt > MaxRateLimitRetries {
+			return accessToken, err
+		}
+		delay := platformErr.RetryDelay
+		if delay <= 0 {
+			delay = backoff
+			backoff = min(2*backoff, rateLimitMaxBackoff)
+		}
+		stepLogger(StepTokenExchange).Warn("Rate limited by the platform, retrying the token exchange", "attempt", attempt, "retry_in", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
 
-	fmt.Println("Initiated exchanging the OIDC Token with CBP token...")
+func exchangeToken(ctx context.Context, config *Config, oidcToken string) (accessToken string, err error) {
+	ctx, span := startSpan(ctx, StepTokenExchange)
+	defer func() { endSpan(span, err) }()
+
+	logger := stepLogger(StepTokenExchange)
+	logger.Info("Initiated exchanging the OIDC Token with CBP token")
 	tokenRequestObj := TokenRequest{
-		Provider: GithubProvider,
+		Provider: getProvider(config),
 		Audience: strings.TrimSuffix(config.CloudBeesApiUrl, "/"), // Optional: omit or override
 	}
+	if tokenRequestObj.Provider == GithubEnterpriseProvider {
+		tokenRequestObj.ServerUrl = getServerUrl(config)
+		if claims, err := decodeClaims(oidcToken); err == nil {
+			tokenRequestObj.Issuer = claims.Issuer
+		}
+	}
 	tokenReqJSON, err := json.Marshal(tokenRequestObj)
 	if err != nil {
-		return fmt.Errorf("error encoding CloudEvent JSON %s", err)
+		return "", fmt.Errorf("error encoding CloudEvent JSON %s", err)
 	}
 
-	tokenReq, _ := http.NewRequest(PostMethod, getExternalTokenExchangeUrl(config), bytes.NewBuffer(tokenReqJSON))
+	tokenReq, _ := http.NewRequestWithContext(ctx, PostMethod, getExternalTokenExchangeUrl(config), bytes.NewBuffer(tokenReqJSON))
 	tokenReq.Header.Set(ContentTypeHeaderKey, ContentTypeCloudEventsJson)
 	tokenReq.Header.Set(AuthorizationHeaderKey, Bearer+oidcToken)
+	injectTraceContext(ctx, tokenReq)
 
 	client := &http.Client{}
 	tokenResp, err := client.Do(tokenReq)
 	if err != nil {
-		return fmt.Errorf("error sending CloudEvent to platform - %s", err.Error())
+		return "", fmt.Errorf("error sending CloudEvent to platform - %s", err.Error())
 	}
 
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
-			fmt.Println("Error closing response body:", err)
+			logger.Warn("Error closing response body", "error", err)
 		}
 	}(tokenResp.Body)
 
 	bodyBytes, err := io.ReadAll(tokenResp.Body)
 	if err != nil {
-		return fmt.Errorf("error reading response body: %w", err)
+		return "", fmt.Errorf("error reading response body: %w", err)
 	}
+	span.SetAttributes(attribute.Int(AttributeHttpStatus, tokenResp.StatusCode))
+	logger.Debug("Token exchange response received", LogHttpStatus, tokenResp.StatusCode)
 	if tokenResp.StatusCode != http.StatusOK {
-		bodyObj := ErrorResponse{}
-		msg := string(bodyBytes)
-		if err := json.Unmarshal(bodyBytes, &bodyObj); err == nil && bodyObj.Message != "" {
-			msg = bodyObj.Message
-		}
-		return fmt.Errorf("error during token exchange - %s : %s", tokenResp.Status, msg)
+		platformErr := newPlatformError(ctx, "error during token exchange", tokenResp, bodyBytes)
+		logger.Error("Token exchange failed", append(platformErr.LogAttrs(), "message", platformErr.Message)...)
+		return "", platformErr
 	}
 
 	var respMap map[string]interface{}
 	if err := json.Unmarshal(bodyBytes, &respMap); err != nil {
-		return fmt.Errorf("failed to parse token exchange response: %w", err)
+		return "", fmt.Errorf("failed to parse token exchange response: %w", err)
 	}
 
 	accessToken, ok := respMap[AccessToken].(string)
 	if !ok || accessToken == "" {
-		return fmt.Errorf("accessToken missing or invalid in response")
+		return "", fmt.Errorf("accessToken missing or invalid in response")
 	}
-	log.Println(base64.StdEncoding.EncodeToString([]byte(accessToken)))
-	// Write the token to a file
-	err = os.WriteFile("access_token.txt", []byte(accessToken), 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write token to file: %w", err)
-	}
-	fmt.Println("Token exchange successful!")
-	data, err := os.ReadFile("access_token.txt")
-	if err != nil {
-		log.Fatalf("Failed to read file: %v", err)
-	}
-	fmt.Println("Access token from file:", string(data))
+	logger.Info("Token exchange successful")
+	return accessToken, nil
+}
+
+func postCloudEvent(ctx context.Context, config *Config, cloudEvent cloudevents.Event, accessToken string) (result *RegistrationResult, err error) {
+	ctx, span := startSpan(ctx, StepSendEvent, attribute.String(AttributeEventId, cloudEvent.ID()))
+	defer func() { endSpan(span, err) }()
 
-	fmt.Println("Initiated sending the CloudEvent to platform...")
+	logger := stepLogger(StepSendEvent).With(LogEventId, cloudEvent.ID())
+	logger.Info("Initiated sending the CloudEvent to platform")
 	eventJSON, err := json.Marshal(cloudEvent)
-	fmt.Println(PrettyPrint(cloudEvent))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CloudEvent: %w", err)
+	}
+	logger.Debug("CloudEvent prepared", "event", string(eventJSON))
 
-	eventReq, err := http.NewRequest(PostMethod, getExternalEventlUrl(config), bytes.NewBuffer(eventJSON))
+	eventReq, err := http.NewRequestWithContext(ctx, PostMethod, getExternalEventlUrl(config), bytes.NewBuffer(eventJSON))
 	if err != nil {
-		return fmt.Errorf("failed to create event request: %w", err)
+		return nil, fmt.Errorf("failed to create event request: %w", err)
 	}
 
 	eventReq.Header.Set(ContentTypeHeaderKey, ContentTypeCloudEventsJson)
 	eventReq.Header.Set(AuthorizationHeaderKey, Bearer+accessToken)
+	eventReq.Header.Set(IdempotencyKeyHeader, cloudEvent.ID())
+	injectTraceContext(ctx, eventReq)
+	client := &http.Client{}
 	eventResp, err := client.Do(eventReq)
 	if err != nil {
-		return fmt.Errorf("error sending external event: %w", err)
+		return nil, fmt.Errorf("error sending external event: %w", err)
 	}
 	defer eventResp.Body.Close()
 
 	eventBodyBytes, err := io.ReadAll(eventResp.Body)
 	if err != nil {
-		return fmt.Errorf("error reading response body: %w", err)
-	}
-	if eventResp.StatusCode != http.StatusOK {
-		bodyObj := ErrorResponse{}
-		msg := string(eventBodyBytes)
-		if err := json.Unmarshal(eventBodyBytes, &bodyObj); err == nil && bodyObj.Message != "" {
-			msg = bodyObj.Message
-		}
-		return fmt.Errorf("error sending CloudEvent to platform - %s : %s", eventResp.Status, msg)
-	}
-	fmt.Println("CloudEvent sent successfully!")
-	return nil
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	span.SetAttributes(attribute.Int(AttributeHttpStatus, eventResp.StatusCode))
+	if eventResp.StatusCode < 200 || eventResp.StatusCode > 299 {
+		platformErr := newPlatformError(ctx, "error sending CloudEvent to platform", eventResp, eventBodyBytes)
+		logger.Error("Sending CloudEvent failed", append(platformErr.LogAttrs(), "message", platformErr.Message)...)
+		return nil, platformErr
+	}
+	result = &RegistrationResult{EventId: cloudEvent.ID(), Status: eventResp.StatusCode, Platform: config.Platform}
+	parseRegistrationResponse(result, eventBodyBytes)
+	logger.Info("CloudEvent sent successfully", LogHttpStatus, eventResp.StatusCode, "registration_id", result.RegistrationId)
+	return result, nil
 }
 
-func getOIDCToken(cloudbeesUrl string) (string, error) {
-	log.Println(ActionIdTokenRequestToken)
-	encoded := base64.StdEncoding.EncodeToString([]byte(os.Getenv(ActionIdTokenRequestToken)))
-	log.Println(encoded)
-	log.Println(ActionIdTokenRequestUrl)
-	log.Println(os.Getenv(ActionIdTokenRequestUrl))
+func getOIDCToken(ctx context.Context, cloudbeesUrl string) (token string, err error) {
+	ctx, span := startSpan(ctx, StepOidc)
+	defer func() { endSpan(span, err) }()
+
+	logger := stepLogger(StepOidc)
 	oidcToken := os.Getenv(ActionIdTokenRequestToken)
 	oidcBaseURL := os.Getenv(ActionIdTokenRequestUrl)
 	oidcAudience := url.QueryEscape(strings.TrimSuffix(cloudbeesUrl, "/"))
 	oidcURL := fmt.Sprintf("%s?audience=%s", oidcBaseURL, oidcAudience)
 
-	oidcTokenReq, err := http.NewRequest("GET", oidcURL, nil)
+	logger.Debug("Requesting OIDC token", "url", oidcBaseURL, "audience", oidcAudience)
+	oidcTokenReq, err := http.NewRequestWithContext(ctx, "GET", oidcURL, nil)
 	if err != nil {
-		log.Printf("Failed to create OIDC request: %v", err)
+		logger.Error("Failed to create OIDC request", "error", err)
 		return "", err
 	}
 	oidcTokenReq.Header.Add(AuthorizationHeaderKey, Bearer+oidcToken)
 	client := &http.Client{}
 	oidcTokenResp, err := client.Do(oidcTokenReq)
 	if err != nil {
-		log.Printf("Failed to execute OIDC request: %v", err)
+		logger.Error("Failed to execute OIDC request", "error", err)
 		return "", err
 	}
 	defer oidcTokenResp.Body.Close()
 
+	span.SetAttributes(attribute.Int(AttributeHttpStatus, oidcTokenResp.StatusCode))
 	if oidcTokenResp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(oidcTokenResp.Body)
-		log.Printf("OIDC token request failed. Status: %d, Body: %s", oidcTokenResp.StatusCode, string(body))
+		logger.Error("OIDC token request failed", LogHttpStatus, oidcTokenResp.StatusCode, "body", string(body))
 		return "", errors.New("OIDC token request failed")
 	}
 
 	var oidcResp struct{ Value string }
 	if err := json.NewDecoder(oidcTokenResp.Body).Decode(&oidcResp); err != nil {
-		log.Printf("Failed to decode OIDC response: %v", err)
+		logger.Error("Failed to decode OIDC response", "error", err)
 		return "", err
 	}
 	if oidcResp.Value == "" {
-		log.Printf("OIDC token value is empty")
+		logger.Error("OIDC token value is empty")
 		return "", errors.New("OIDC token value is empty")
 	}
-	log.Print("Response Value : ")
-	log.Println(base64.StdEncoding.EncodeToString([]byte(oidcResp.Value)))
 	return oidcResp.Value, nil
 }
 
+func writeCloudEvent(cloudEvent cloudevents.Event, path string) error {
+	eventJSON, err := json.Marshal(cloudEvent)
+	if err != nil {
+		return fmt.Errorf("failed to encode CloudEvent: %w", err)
+	}
+	if err := os.WriteFile(path, eventJSON, 0644); err != nil {
+		return fmt.Errorf("failed to write CloudEvent: %w", err)
+	}
+	slog.Info("CloudEvent written", "path", path)
+	return nil
+}
+
 // PrettyPrint converts the input to JSON string
 func PrettyPrint(in any) string {
 	data, err := json.MarshalIndent(in, "", "  ")
 	if err != nil {
-		fmt.Println("error marshalling response", err)
+		slog.Error("error marshalling response", "error", err)
 	}
 	return string(data)
 }
@@ -3,10 +3,11 @@ package artifacts
 import (
 	"context"
 	"fmt"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
 	"net/http"
 	"net/http/httptest"
 	"os"
-	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -17,7 +18,7 @@ func TestRun(t *testing.T) {
 	t.Run("Missing Env:"+GithubRunId, func(t *testing.T) {
 		var config = Config{}
 
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		fmt.Println(err)
 		assert.NotNil(t, err)
 		assert.Equal(t, err.Error(), GithubRunId+" is not set in the environment")
@@ -27,7 +28,7 @@ func TestRun(t *testing.T) {
 		var config = Config{}
 		os.Setenv(GithubRunId, "123456789")
 
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		fmt.Println(err)
 		assert.NotNil(t, err)
 		assert.Equal(t, err.Error(), GithubRunAttempt+" is not set in the environment")
@@ -37,7 +38,7 @@ func TestRun(t *testing.T) {
 		var config = Config{}
 		os.Setenv(GithubRunId, "123456789")
 		os.Setenv(GithubRunAttempt, "1")
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		fmt.Println(err)
 		assert.NotNil(t, err)
 		assert.Equal(t, err.Error(), CloudbeesApiUrl+" is not set in the environment")
@@ -48,7 +49,7 @@ func TestRun(t *testing.T) {
 		os.Setenv(GithubRunId, "123456789")
 		os.Setenv(GithubRunAttempt, "1")
 		os.Setenv(CloudbeesApiUrl, "https://api-test.cloudbees.com")
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		fmt.Println(err)
 		assert.NotNil(t, err)
 		assert.Equal(t, err.Error(), ArtifactName+" is not set in the environment")
@@ -60,7 +61,7 @@ func TestRun(t *testing.T) {
 		os.Setenv(GithubRunAttempt, "1")
 		os.Setenv(CloudbeesApiUrl, "https://api-test.cloudbees.com")
 		os.Setenv(ArtifactName, "testartifact")
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		fmt.Println(err)
 		assert.NotNil(t, err)
 		assert.Equal(t, err.Error(), ArtifactUrl+" is not set in the environment")
@@ -73,7 +74,7 @@ func TestRun(t *testing.T) {
 		os.Setenv(CloudbeesApiUrl, "https://api-test.cloudbees.com")
 		os.Setenv(ArtifactName, "testartifact")
 		os.Setenv(ArtifactUrl, "https://test.com")
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		fmt.Println(err)
 		assert.NotNil(t, err)
 		assert.Equal(t, err.Error(), ArtifactVersion+" is not set in the environment")
@@ -87,7 +88,7 @@ func TestRun(t *testing.T) {
 		os.Setenv(ArtifactName, "testartifact")
 		os.Setenv(ArtifactUrl, "https://test.com")
 		os.Setenv(ArtifactVersion, "1.0.0")
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		fmt.Println(err)
 		assert.NotNil(t, err)
 		assert.Equal(t, err.Error(), GithubRunNumber+" is not set in the environment")
@@ -102,7 +103,7 @@ func TestRun(t *testing.T) {
 		os.Setenv(ArtifactUrl, "https://test.com")
 		os.Setenv(ArtifactVersion, "1.0.0")
 		os.Setenv(GithubRunNumber, "123")
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		fmt.Println(err)
 		assert.NotNil(t, err)
 		assert.Equal(t, err.Error(), GithubRepository+" is not set in the environment")
@@ -118,7 +119,7 @@ func TestRun(t *testing.T) {
 		os.Setenv(ArtifactVersion, "1.0.0")
 		os.Setenv(GithubRunNumber, "123")
 		os.Setenv(GithubRepository, "test/test")
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		fmt.Println(err)
 		assert.NotNil(t, err)
 		assert.Equal(t, err.Error(), GithubWorkflowRef+" is not set in the environment")
@@ -136,7 +137,7 @@ func TestRun(t *testing.T) {
 		os.Setenv(GithubRepository, "test/test")
 		os.Setenv(GithubWorkflowRef, "SrimanPadmanabanCB/gha-action/.github/workflows/test_action.yml@refs/heads/main")
 
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		fmt.Println(err)
 		assert.NotNil(t, err)
 		assert.Equal(t, err.Error(), GithubJobName+" is not set in the environment")
@@ -153,28 +154,26 @@ func TestRun(t *testing.T) {
 		os.Setenv(GithubWorkflowRef, "SrimanPadmanabanCB/gha-action/.github/workflows/test_action.yml@refs/heads/main")
 		os.Setenv(GithubJobName, "testjob")
 
-		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			fmt.Println("Mock Request URL:", r.URL.String())
-			switch {
-			case r.Method == "GET" && strings.HasPrefix(r.URL.String(), "/?audience="):
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte(`{"value": "mock-oidc-token"}`))
-			case r.Method == "POST" && r.URL.Path == "/token-exchange/external-oidc-id-token":
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte(`{"accessToken": "mock-cbp-token"}`))
-			case r.Method == "POST" && r.URL.Path == "/v3/external-events":
-				w.WriteHeader(http.StatusOK)
-			default:
-				http.Error(w, "unexpected request: "+r.URL.Path, http.StatusNotFound)
-			}
-		}))
-		defer ts.Close()
+		server := testserver.New(t, platformtest.Config{})
 
-		os.Setenv(CloudbeesApiUrl, ts.URL)
-		os.Setenv(ActionIdTokenRequestUrl, ts.URL)
-		config.CloudBeesApiUrl = ts.URL
-		err := config.Run(context.Background())
+		os.Setenv(CloudbeesApiUrl, server.URL)
+		os.Setenv(ActionIdTokenRequestUrl, server.OIDCUrl())
+		config.CloudBeesApiUrl = server.URL
+		_, err := config.Run(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, server.Events(), 1)
+		assert.Equal(t, BuildArtifactType, server.Events()[0].Type())
+	})
+
+	t.Run("Config over environment", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+
+		_, err := (&Config{ArtifactName: "configured", ArtifactVersion: "2.0.0"}).Run(context.Background())
 		assert.Nil(t, err)
+		assert.Equal(t, "configured", server.Artifacts()[0].ArtifactName)
+		assert.Equal(t, "2.0.0", server.Artifacts()[0].ArtifactVersion)
+		assert.Equal(t, "https://test.com", server.Artifacts()[0].ArtifactUrl)
 	})
 
 	t.Run("Success All Fields", func(t *testing.T) {
@@ -190,31 +189,18 @@ func TestRun(t *testing.T) {
 		os.Setenv(GithubServerUrl, "https://github.com")
 		os.Setenv(GithubJobName, "testjob")
 
-		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			fmt.Println("Mock Request URL:", r.URL.String())
-			switch {
-			case r.Method == "GET" && strings.HasPrefix(r.URL.String(), "/?audience="):
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte(`{"value": "mock-oidc-token"}`))
-			case r.Method == "POST" && r.URL.Path == "/token-exchange/external-oidc-id-token":
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte(`{"accessToken": "mock-cbp-token"}`))
-			case r.Method == "POST" && r.URL.Path == "/v3/external-events":
-				w.WriteHeader(http.StatusOK)
-			default:
-				http.Error(w, "unexpected request: "+r.URL.Path, http.StatusNotFound)
-			}
-		}))
-		defer ts.Close()
+		server := testserver.New(t, platformtest.Config{})
 
 		// Set the httpClient to use the test server
-		os.Setenv(CloudbeesApiUrl, ts.URL)
-		os.Setenv(ActionIdTokenRequestUrl, ts.URL)
-		config.CloudBeesApiUrl = ts.URL
+		os.Setenv(CloudbeesApiUrl, server.URL)
+		os.Setenv(ActionIdTokenRequestUrl, server.OIDCUrl())
+		config.CloudBeesApiUrl = server.URL
 
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		assert.Nil(t, err)
 		//assert.Equal(t, err.Error(), GithubWorkflowRef+" is not set in the environment")
+		assert.Len(t, server.Events(), 1)
+		assert.Equal(t, "https://github.com/SrimanPadmanabanCB/gha-action", server.Events()[0].Source())
 	})
 
 	t.Run("Failed OIDC token request", func(t *testing.T) {
@@ -229,17 +215,16 @@ func TestRun(t *testing.T) {
 		os.Setenv(GithubWorkflowRef, "SrimanPadmanabanCB/gha-action/.github/workflows/test_action.yml@refs/heads/main")
 		os.Setenv(GithubJobName, "testjob")
 
-		// Create a test server
-		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusBadGateway)
-		}))
-		defer ts.Close()
+		// Create a mock platform
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.OIDCEndpoint: {Status: http.StatusBadGateway},
+		}})
 
-		os.Setenv(CloudbeesApiUrl, ts.URL)
-		os.Setenv(ActionIdTokenRequestUrl, ts.URL)
-		config.CloudBeesApiUrl = ts.URL
+		os.Setenv(CloudbeesApiUrl, server.URL)
+		os.Setenv(ActionIdTokenRequestUrl, server.OIDCUrl())
+		config.CloudBeesApiUrl = server.URL
 
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "OIDC token request failed")
 	})
@@ -256,18 +241,16 @@ func TestRun(t *testing.T) {
 		os.Setenv(GithubWorkflowRef, "SrimanPadmanabanCB/gha-action/.github/workflows/test_action.yml@refs/heads/main")
 		os.Setenv(GithubJobName, "testjob")
 
-		// Create a test server
-		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"value": ""}`))
-		}))
-		defer ts.Close()
+		// Create a mock platform
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.OIDCEndpoint: {Body: `{"value": ""}`},
+		}})
 
-		os.Setenv(CloudbeesApiUrl, ts.URL)
-		os.Setenv(ActionIdTokenRequestUrl, ts.URL)
-		config.CloudBeesApiUrl = ts.URL
+		os.Setenv(CloudbeesApiUrl, server.URL)
+		os.Setenv(ActionIdTokenRequestUrl, server.OIDCUrl())
+		config.CloudBeesApiUrl = server.URL
 
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		fmt.Println(err)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "OIDC token value is empty")
@@ -285,18 +268,16 @@ func TestRun(t *testing.T) {
 		os.Setenv(GithubWorkflowRef, "SrimanPadmanabanCB/gha-action/.github/workflows/test_action.yml@refs/heads/main")
 		os.Setenv(GithubJobName, "testjob")
 
-		// Create a test server
-		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"value": 1}`))
-		}))
-		defer ts.Close()
+		// Create a mock platform
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.OIDCEndpoint: {Body: `{"value": 1}`},
+		}})
 
-		os.Setenv(CloudbeesApiUrl, ts.URL)
-		os.Setenv(ActionIdTokenRequestUrl, ts.URL)
-		config.CloudBeesApiUrl = ts.URL
+		os.Setenv(CloudbeesApiUrl, server.URL)
+		os.Setenv(ActionIdTokenRequestUrl, server.OIDCUrl())
+		config.CloudBeesApiUrl = server.URL
 
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		fmt.Println(err)
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "failed to create oidc token")
@@ -322,7 +303,7 @@ func TestRun(t *testing.T) {
 
 		os.Setenv(CloudbeesApiUrl, ts.URL)
 		config.CloudBeesApiUrl = ts.URL
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "failed to create oidc token")
 	})
@@ -339,18 +320,16 @@ func TestRun(t *testing.T) {
 		os.Setenv(GithubWorkflowRef, "SrimanPadmanabanCB/gha-action/.github/workflows/test_action.yml@refs/heads/main")
 		os.Setenv(GithubJobName, "testjob")
 
-		// Create a test server
-		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"value": "mock-oidc-token"}`))
-		}))
-		defer ts.Close()
+		// Create a mock platform
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.TokenExchangeEndpoint: {Body: `{"value": "mock-oidc-token"}`},
+		}})
 
-		os.Setenv(CloudbeesApiUrl, ts.URL)
-		os.Setenv(ActionIdTokenRequestUrl, ts.URL)
-		config.CloudBeesApiUrl = ts.URL
+		os.Setenv(CloudbeesApiUrl, server.URL)
+		os.Setenv(ActionIdTokenRequestUrl, server.OIDCUrl())
+		config.CloudBeesApiUrl = server.URL
 
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "accessToken missing or invalid in response")
 	})
@@ -367,16 +346,12 @@ func TestRun(t *testing.T) {
 		os.Setenv(GithubWorkflowRef, "SrimanPadmanabanCB/gha-action/.github/workflows/test_action.yml@refs/heads/main")
 		os.Setenv(GithubJobName, "testjob")
 
-		// Create a test server
-		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"value": "mock-oidc-token"}`))
-		}))
-		defer ts.Close()
+		// Create a mock platform
+		server := testserver.New(t, platformtest.Config{})
 
 		os.Setenv(CloudbeesApiUrl, "test")
-		os.Setenv(ActionIdTokenRequestUrl, ts.URL)
-		err := config.Run(context.Background())
+		os.Setenv(ActionIdTokenRequestUrl, server.OIDCUrl())
+		_, err := config.Run(context.Background())
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "error sending CloudEvent to platform")
 	})
@@ -393,26 +368,16 @@ func TestRun(t *testing.T) {
 		os.Setenv(GithubWorkflowRef, "SrimanPadmanabanCB/gha-action/.github/workflows/test_action.yml@refs/heads/main")
 		os.Setenv(GithubJobName, "testjob")
 
-		// Create a test server
-		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			fmt.Println("Mock Request URL:", r.URL.String())
-			switch {
-			case r.Method == "GET" && strings.HasPrefix(r.URL.String(), "/?audience="):
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte(`{"value": "mock-oidc-token"}`))
-			case r.Method == "POST" && r.URL.Path == "/token-exchange/external-oidc-id-token":
-				w.WriteHeader(http.StatusBadGateway)
-			default:
-				http.Error(w, "unexpected request: "+r.URL.Path, http.StatusNotFound)
-			}
-		}))
-		defer ts.Close()
+		// Create a mock platform
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.TokenExchangeEndpoint: {Status: http.StatusBadGateway},
+		}})
 
-		os.Setenv(CloudbeesApiUrl, ts.URL)
-		os.Setenv(ActionIdTokenRequestUrl, ts.URL)
-		config.CloudBeesApiUrl = ts.URL
+		os.Setenv(CloudbeesApiUrl, server.URL)
+		os.Setenv(ActionIdTokenRequestUrl, server.OIDCUrl())
+		config.CloudBeesApiUrl = server.URL
 
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "error during token exchange - 502 Bad Gateway :")
 	})
@@ -428,30 +393,33 @@ func TestRun(t *testing.T) {
 		os.Setenv(GithubWorkflowRef, "SrimanPadmanabanCB/gha-action/.github/workflows/test_action.yml@refs/heads/main")
 		os.Setenv(GithubJobName, "testjob")
 
-		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			fmt.Println("Mock Request URL:", r.URL.String())
-			switch {
-			case r.Method == "GET" && strings.HasPrefix(r.URL.String(), "/?audience="):
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte(`{"value": "mock-oidc-token"}`))
-			case r.Method == "POST" && r.URL.Path == "/token-exchange/external-oidc-id-token":
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte(`{"accessToken": "mock-cbp-token"}`))
-			case r.Method == "POST" && r.URL.Path == "/v3/external-events":
-				http.Error(w, "test error", http.StatusBadGateway)
-			default:
-				http.Error(w, "unexpected request: "+r.URL.Path, http.StatusNotFound)
-			}
-		}))
-		defer ts.Close()
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.EventsEndpoint: {Status: http.StatusBadGateway, Body: "test error"},
+		}})
 
 		// Set the httpClient to use the test server
-		os.Setenv(CloudbeesApiUrl, ts.URL)
-		os.Setenv(ActionIdTokenRequestUrl, ts.URL)
-		config.CloudBeesApiUrl = ts.URL
+		os.Setenv(CloudbeesApiUrl, server.URL)
+		os.Setenv(ActionIdTokenRequestUrl, server.OIDCUrl())
+		config.CloudBeesApiUrl = server.URL
 
-		err := config.Run(context.Background())
+		_, err := config.Run(context.Background())
 		assert.NotNil(t, err)
 		assert.Contains(t, err.Error(), "error sending CloudEvent to platform - 502 Bad Gateway :")
 	})
 }
+
+// setTestEnv sets the environment of a successful registration against server.
+func setTestEnv(t *testing.T, server *testserver.Server) {
+	t.Setenv(GithubRunId, "123456789")
+	t.Setenv(GithubRunAttempt, "1")
+	t.Setenv(ArtifactName, "testartifact")
+	t.Setenv(ArtifactUrl, "https://test.com")
+	t.Setenv(ArtifactVersion, "1.0.0")
+	t.Setenv(GithubRunNumber, "123")
+	t.Setenv(GithubRepository, "SrimanPadmanabanCB/gha-action")
+	t.Setenv(GithubWorkflowRef, "SrimanPadmanabanCB/gha-action/.github/workflows/test_action.yml@refs/heads/main")
+	t.Setenv(GithubServerUrl, "https://github.com")
+	t.Setenv(GithubJobName, "testjob")
+	t.Setenv(CloudbeesApiUrl, server.URL)
+	t.Setenv(ActionIdTokenRequestUrl, server.OIDCUrl())
+}
@@ -0,0 +1,216 @@
+package artifacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	templatePlaceholderRegexp = regexp.MustCompile(`\{([a-z][a-z0-9_]*)\}`)
+	// placeholderPatterns are the filename template placeholders with a known
+	// shape. Other placeholders match any part of the file name.
+	placeholderPatterns = map[string]string{
+		"name":    `.+?`,
+		"version": `v?\d[^/]*?`,
+		"ext":     `tar\.[a-z0-9]+|[^.]+`,
+	}
+)
+
+// RunFromDir registers each file of FromDir matching Globs. The name and
+// version are read from the file name with FilenameTemplate, falling back to
+// the name and version of the config, then ARTIFACT_NAME and ARTIFACT_VERSION,
+// and the URL is built from UrlTemplate.
+func (config *Config) RunFromDir(ctx context.Context) ([]*RegistrationResult, error) {
+	artifacts, err := scanDir(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return config.RunBatch(ctx, artifacts)
+}
+
+// scanDir lists the artifacts of the files matching the globs, in lexical
+// order of their path.
+func scanDir(ctx context.Context, config *Config) (artifacts []ArtifactInfo, err error) {
+	_, span := startSpan(ctx, StepScan, attribute.String("scan.dir", config.FromDir))
+	defer func() { endSpan(span, err) }()
+
+	if config.UrlTemplate == "" {
+		return nil, fmt.Errorf("a url template is required to register the files of %s", config.FromDir)
+	}
+	globs := config.Globs
+	if len(globs) == 0 {
+		globs = []string{"*"}
+	}
+	for _, glob := range globs {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+	}
+	filenameTemplate := firstNonEmpty(config.FilenameTemplate, DefaultFilenameTemplate)
+	filenameRegexp, err := compileFilenameTemplate(filenameTemplate)
+	if err != nil {
+		return nil, err
+	}
+	defaultName := firstNonEmpty(config.ArtifactName, os.Getenv(ArtifactName))
+	defaultVersion := firstNonEmpty(config.ArtifactVersion, os.Getenv(ArtifactVersion))
+
+	logger := stepLogger(StepScan)
+	err = filepath.WalkDir(config.FromDir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(config.FromDir, file)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		if !matchesGlob(globs, relativePath) {
+			return nil
+		}
+
+		match := filenameRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return fmt.Errorf("file %s does not match filename template %q", relativePath, filenameTemplate)
+		}
+		values := map[string]string{"file": entry.Name(), "path": relativePath}
+		for i, placeholder := range filenameRegexp.SubexpNames() {
+			if placeholder != "" {
+				values[placeholder] = match[i]
+			}
+		}
+		values["name"] = firstNonEmpty(values["name"], defaultName)
+		if values["name"] == "" {
+			return fmt.Errorf("no artifact name for %s, add {name} to the filename template or set %s", relativePath, ArtifactName)
+		}
+		values["version"] = firstNonEmpty(values["version"], defaultVersion)
+		if values["version"] == "" {
+			return fmt.Errorf("no artifact version for %s, add {version} to the filename template or set %s", relativePath, ArtifactVersion)
+		}
+		values["digest"], err = fileDigest(file)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		artifactUrl, err := expandUrlTemplate(config.UrlTemplate, values)
+		if err != nil {
+			return err
+		}
+
+		logger.Debug("Found artifact file", "path", relativePath, "name", values["name"], "version", values["version"], "digest", values["digest"])
+		artifacts = append(artifacts, ArtifactInfo{
+			ArtifactName:    values["name"],
+			ArtifactUrl:     artifactUrl,
+			ArtifactVersion: values["version"],
+			ArtifactDigest:  values["digest"],
+			ArtifactSize:    info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", config.FromDir, err)
+	}
+	if len(artifacts) == 0 {
+		return nil, fmt.Errorf("no files in %s match %s", config.FromDir, strings.Join(globs, ", "))
+	}
+	span.SetAttributes(attribute.Int("scan.files", len(artifacts)))
+	logger.Info("Found artifact files", "dir", config.FromDir, "files", len(artifacts))
+	return artifacts, nil
+}
+
+// matchesGlob matches globs with a slash against the path relative to the
+// scanned directory and the others against the file name.
+func matchesGlob(globs []string, relativePath string) bool {
+	return slices.ContainsFunc(globs, func(glob string) bool {
+		name := relativePath
+		if !strings.Contains(glob, "/") {
+			name = path.Base(relativePath)
+		}
+		matched, _ := path.Match(glob, name)
+		return matched
+	})
+}
+
+// compileFilenameTemplate turns a template such as {name}_{version}_{os}.{ext}
+// into a regular expression with a group per placeholder.
+func compileFilenameTemplate(template string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	seen := map[string]bool{}
+	last := 0
+	for _, match := range templatePlaceholderRegexp.FindAllStringSubmatchIndex(template, -1) {
+		placeholder := template[match[2]:match[3]]
+		if seen[placeholder] {
+			return nil, fmt.Errorf("placeholder {%s} is used twice in filename template %q", placeholder, template)
+		}
+		seen[placeholder] = true
+		pattern, ok := placeholderPatterns[placeholder]
+		if !ok {
+			pattern = `[^/]+?`
+		}
+		sb.WriteString(regexp.QuoteMeta(template[last:match[0]]))
+		fmt.Fprintf(&sb, "(?P<%s>%s)", placeholder, pattern)
+		last = match[1]
+	}
+	sb.WriteString(regexp.QuoteMeta(template[last:]))
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// expandUrlTemplate replaces the placeholders of the URL template with the
+// escaped values of the file. The segments of {path} are escaped separately.
+func expandUrlTemplate(template string, values map[string]string) (string, error) {
+	var err error
+	expanded := templatePlaceholderRegexp.ReplaceAllStringFunc(template, func(match string) string {
+		placeholder := strings.Trim(match, "{}")
+		value, ok := values[placeholder]
+		if !ok && err == nil {
+			placeholders := make([]string, 0, len(values))
+			for placeholder := range values {
+				placeholders = append(placeholders, "{"+placeholder+"}")
+			}
+			slices.Sort(placeholders)
+			err = fmt.Errorf("unknown placeholder %s in url template, expected one of %s", match, strings.Join(placeholders, ", "))
+		}
+		if placeholder == "path" {
+			segments := strings.Split(value, "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+			return strings.Join(segments, "/")
+		}
+		return url.PathEscape(value)
+	})
+	return expanded, err
+}
+
+func fileDigest(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
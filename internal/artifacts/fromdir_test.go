@@ -0,0 +1,162 @@
+package artifacts
+
+import (
+	"context"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileFilenameTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		file     string
+		expected map[string]string
+	}{
+		{DefaultFilenameTemplate, "app-1.2.3.tar.gz", map[string]string{"name": "app", "version": "1.2.3", "ext": "tar.gz"}},
+		{DefaultFilenameTemplate, "my-app-linux-amd64-v2.0.0-rc.1.zip", map[string]string{"name": "my-app-linux-amd64", "version": "v2.0.0-rc.1", "ext": "zip"}},
+		{"{name}_{version}_{os}_{arch}.{ext}", "cli_1.0.0_darwin_arm64.tar.gz", map[string]string{"name": "cli", "version": "1.0.0", "os": "darwin", "arch": "arm64", "ext": "tar.gz"}},
+		{"{name}.jar", "service.jar", map[string]string{"name": "service"}},
+	}
+	for _, test := range tests {
+		filenameRegexp, err := compileFilenameTemplate(test.template)
+		assert.Nil(t, err)
+		match := filenameRegexp.FindStringSubmatch(test.file)
+		assert.NotNil(t, match, test.file)
+		values := map[string]string{}
+		for i, placeholder := range filenameRegexp.SubexpNames() {
+			if placeholder != "" {
+				values[placeholder] = match[i]
+			}
+		}
+		assert.Equal(t, test.expected, values)
+	}
+
+	filenameRegexp, err := compileFilenameTemplate(DefaultFilenameTemplate)
+	assert.Nil(t, err)
+	assert.False(t, filenameRegexp.MatchString("checksums.txt"))
+
+	_, err = compileFilenameTemplate("{name}-{name}.zip")
+	assert.EqualError(t, err, `placeholder {name} is used twice in filename template "{name}-{name}.zip"`)
+}
+
+func TestExpandUrlTemplate(t *testing.T) {
+	values := map[string]string{"file": "app-1.0.0.zip", "version": "1.0.0"}
+	url, err := expandUrlTemplate("https://example.com/v{version}/{file}", values)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/v1.0.0/app-1.0.0.zip", url)
+
+	url, err = expandUrlTemplate("https://example.com/{path}", map[string]string{"path": "linux amd64/app#1?.zip"})
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/linux%20amd64/app%231%3F.zip", url)
+
+	_, err = expandUrlTemplate("https://example.com/{tag}/{file}", values)
+	assert.EqualError(t, err, "unknown placeholder {tag} in url template, expected one of {file}, {version}")
+}
+
+func TestRunFromDir(t *testing.T) {
+	dist := t.TempDir()
+	writeDistFile := func(name string, content string) {
+		file := filepath.Join(dist, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(file), 0755))
+		assert.Nil(t, os.WriteFile(file, []byte(content), 0644))
+	}
+	writeDistFile("cli_2.0.0_linux_amd64.tar.gz", "linux")
+	writeDistFile("cli_2.0.0_darwin_arm64.tar.gz", "darwin")
+	writeDistFile("checksums.txt", "checksums")
+	writeDistFile("extra/cli_2.0.0_windows_amd64.zip", "windows")
+
+	config := Config{
+		FromDir:          dist,
+		Globs:            []string{"*.tar.gz", "extra/*.zip"},
+		FilenameTemplate: "{name}_{version}_{os}_{arch}.{ext}",
+		UrlTemplate:      "https://github.com/owner/cli/releases/download/v{version}/{path}",
+	}
+
+	t.Run("Registers each file", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+
+		results, err := config.RunFromDir(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, results, 3)
+		assert.Len(t, server.Events(), 3)
+
+		var urls []string
+		for i, artifact := range server.Artifacts() {
+			assert.Equal(t, results[i].RegistrationId, artifact.Id)
+			assert.Equal(t, "cli", artifact.ArtifactName)
+			assert.Equal(t, "2.0.0", artifact.ArtifactVersion)
+			urls = append(urls, artifact.ArtifactUrl)
+		}
+		assert.Equal(t, []string{
+			"https://github.com/owner/cli/releases/download/v2.0.0/cli_2.0.0_darwin_arm64.tar.gz",
+			"https://github.com/owner/cli/releases/download/v2.0.0/cli_2.0.0_linux_amd64.tar.gz",
+			"https://github.com/owner/cli/releases/download/v2.0.0/extra/cli_2.0.0_windows_amd64.zip",
+		}, urls)
+		digest, err := fileDigest(filepath.Join(dist, "cli_2.0.0_darwin_arm64.tar.gz"))
+		assert.Nil(t, err)
+		assert.Equal(t, digest, server.Artifacts()[0].ArtifactDigest)
+	})
+
+	t.Run("Version from the environment", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+
+		config := Config{FromDir: dist, Globs: []string{"*.txt"}, FilenameTemplate: "{name}.txt", UrlTemplate: "https://example.com/{version}/{file}"}
+		_, err := config.RunFromDir(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, "checksums", server.Artifacts()[0].ArtifactName)
+		assert.Equal(t, "1.0.0", server.Artifacts()[0].ArtifactVersion)
+		assert.Equal(t, "https://example.com/1.0.0/checksums.txt", server.Artifacts()[0].ArtifactUrl)
+	})
+
+	t.Run("Version from the config", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+
+		config := Config{FromDir: dist, Globs: []string{"*.txt"}, FilenameTemplate: "{name}.txt", UrlTemplate: "https://example.com/{version}/{file}", ArtifactVersion: "2.0.0"}
+		_, err := config.RunFromDir(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, "2.0.0", server.Artifacts()[0].ArtifactVersion)
+	})
+
+	t.Run("File not matching the template", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+
+		config := Config{FromDir: dist, UrlTemplate: "https://example.com/{file}"}
+		_, err := config.RunFromDir(context.Background())
+		assert.EqualError(t, err, `failed to scan `+dist+`: file checksums.txt does not match filename template "{name}-{version}.{ext}"`)
+		assert.Empty(t, server.Events())
+	})
+
+	t.Run("No matching files", func(t *testing.T) {
+		_, err := (&Config{FromDir: dist, Globs: []string{"*.deb"}, UrlTemplate: "https://example.com/{file}"}).RunFromDir(context.Background())
+		assert.EqualError(t, err, "no files in "+dist+" match *.deb")
+	})
+
+	t.Run("Missing url template", func(t *testing.T) {
+		_, err := (&Config{FromDir: dist}).RunFromDir(context.Background())
+		assert.EqualError(t, err, "a url template is required to register the files of "+dist)
+	})
+
+	t.Run("Failed registration", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.EventsEndpoint: {Status: http.StatusBadRequest, Count: 1},
+		}})
+		setTestEnv(t, server)
+
+		results, err := config.RunFromDir(context.Background())
+		assert.ErrorContains(t, err, "failed to register cli@2.0.0: ")
+		assert.Len(t, results, 3)
+		assert.NotEmpty(t, results[0].Error)
+		assert.Empty(t, results[1].Error)
+		assert.Empty(t, results[2].Error)
+	})
+}
@@ -0,0 +1,83 @@
+package artifacts
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// isEnterpriseServer reports whether the workflow runs on GitHub Enterprise
+// Server rather than github.com or a GHE.com tenant.
+func isEnterpriseServer(config *Config) bool {
+	return getServerUrl(config) != DefaultGithubServerUrl && getGheComHost(config) == ""
+}
+
+// getGheComHost returns the host of a GHE.com data residency tenant, e.g.
+// acme.ghe.com, and "" for other servers.
+func getGheComHost(config *Config) string {
+	serverUrl, err := url.Parse(getServerUrl(config))
+	if err != nil || !strings.HasSuffix(serverUrl.Host, GheComDomain) {
+		return ""
+	}
+	return serverUrl.Host
+}
+
+// getProvider returns the identity provider passed to the token exchange.
+// The platform only documents the GitHub provider, so GHES tokens are sent
+// with the enterprise provider only when the exchange is enabled for GHES.
+func getProvider(config *Config) string {
+	if config.GhesTokenExchange && isEnterpriseServer(config) {
+		return GithubEnterpriseProvider
+	}
+	return GithubProvider
+}
+
+// getOidcIssuer returns the expected issuer of the Actions OIDC tokens. GHES
+// issues them from its own token service and GHE.com tenants from
+// token.actions.<tenant>.ghe.com.
+func getOidcIssuer(config *Config) string {
+	if config.OidcIssuer != "" {
+		return strings.TrimSuffix(config.OidcIssuer, "/")
+	}
+	if host := getGheComHost(config); host != "" {
+		return "https://token.actions." + host
+	}
+	if isEnterpriseServer(config) {
+		return getServerUrl(config) + GhesTokenIssuerPath
+	}
+	return DefaultOidcIssuer
+}
+
+// loadServerUrl sets the GitHub server from the environment for commands that
+// authenticate without validating the full registration environment.
+func loadServerUrl(config *Config) {
+	if config.GhaServerUrl == "" {
+		config.GhaServerUrl = os.Getenv(GithubServerUrl)
+	}
+}
+
+// validateTokenIssuer checks the iss claim of the OIDC token against the
+// issuer of the GitHub server when oidc-issuer or ghes-token-exchange is set.
+// Otherwise the platform validates the token. github.com enterprises may use a
+// customized issuer of the form <issuer>/<enterprise>.
+func validateTokenIssuer(config *Config, token string) error {
+	if config.OidcIssuer == "" && !config.GhesTokenExchange {
+		return nil
+	}
+	claims, err := decodeClaims(token)
+	if err != nil {
+		return err
+	}
+	tokenIssuer := claims.Issuer
+	issuer := getOidcIssuer(config)
+	if tokenIssuer == issuer {
+		return nil
+	}
+	if config.OidcIssuer == "" && !isEnterpriseServer(config) {
+		if enterprise, found := strings.CutPrefix(tokenIssuer, issuer+"/"); found && enterprise != "" && !strings.Contains(enterprise, "/") {
+			return nil
+		}
+	}
+	return fmt.Errorf("OIDC token issuer %q does not match the expected issuer %q of %s", tokenIssuer, issuer, getServerUrl(config))
+}
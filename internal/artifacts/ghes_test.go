@@ -0,0 +1,84 @@
+package artifacts
+
+import (
+	"context"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnterpriseServer(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		serverUrl    string
+		ghesExchange bool
+		provider     string
+		issuer       string
+		apiUrl       string
+	}{
+		{"github.com", "https://github.com", true, GithubProvider, DefaultOidcIssuer, DefaultGithubApiUrl},
+		{"Default server", "", true, GithubProvider, DefaultOidcIssuer, DefaultGithubApiUrl},
+		{"GHES", "https://ghes.example.com/", false, GithubProvider, "https://ghes.example.com/_services/token", "https://ghes.example.com/api/v3"},
+		{"GHES token exchange", "https://ghes.example.com/", true, GithubEnterpriseProvider, "https://ghes.example.com/_services/token", "https://ghes.example.com/api/v3"},
+		{"GHE.com", "https://acme.ghe.com", true, GithubProvider, "https://token.actions.acme.ghe.com", "https://api.acme.ghe.com"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &Config{GhaServerUrl: tc.serverUrl, GhesTokenExchange: tc.ghesExchange}
+			assert.Equal(t, tc.provider, getProvider(config))
+			assert.Equal(t, tc.issuer, getOidcIssuer(config))
+			assert.Equal(t, tc.apiUrl, getGithubApiUrl(config))
+		})
+	}
+}
+
+func TestTokenIssuer(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		serverUrl    string
+		issuer       string
+		oidcIssuer   string
+		ghesExchange bool
+		providers    []string
+		err          string
+	}{
+		{"github.com", "https://github.com", platformtest.DefaultIssuer, "", false, nil, ""},
+		{"github.com enterprise issuer", "https://github.com", platformtest.DefaultIssuer + "/acme", "", false, nil, ""},
+		{"GHES", "https://ghes.example.com", "https://ghes.example.com/_services/token", "", false, nil, ""},
+		{"Custom issuer", "https://ghes.example.com", "https://token.example.com", "https://token.example.com/", false, nil, ""},
+		{"GHES token exchange", "https://ghes.example.com", "https://ghes.example.com/_services/token", "", true, []string{GithubEnterpriseProvider}, ""},
+		{"GHES token exchange unsupported", "https://ghes.example.com", "https://ghes.example.com/_services/token", "", true, nil,
+			`unsupported provider "GITHUB_ENTERPRISE"`},
+		{"GHE.com", "https://acme.ghe.com", "https://token.actions.acme.ghe.com", "", true, nil, ""},
+		{"Unchecked issuer", "https://acme.ghe.com", platformtest.DefaultIssuer, "", false, nil, ""},
+		{"GHE.com with github.com token", "https://acme.ghe.com", platformtest.DefaultIssuer, "", true, nil,
+			`OIDC token issuer "https://token.actions.githubusercontent.com" does not match the expected issuer "https://token.actions.acme.ghe.com" of https://acme.ghe.com`},
+		{"GHES with github.com token", "https://ghes.example.com", platformtest.DefaultIssuer, "", true, []string{GithubEnterpriseProvider},
+			`OIDC token issuer "https://token.actions.githubusercontent.com" does not match the expected issuer "https://ghes.example.com/_services/token" of https://ghes.example.com`},
+		{"github.com with GHES token", "https://github.com", "https://ghes.example.com/_services/token", platformtest.DefaultIssuer, false, nil,
+			`OIDC token issuer "https://ghes.example.com/_services/token" does not match the expected issuer "https://token.actions.githubusercontent.com" of https://github.com`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := testserver.New(t, platformtest.Config{Issuer: tc.issuer, Providers: tc.providers})
+			setTestEnv(t, server)
+			t.Setenv(GithubServerUrl, tc.serverUrl)
+
+			_, err := (&Config{OidcIssuer: tc.oidcIssuer, GhesTokenExchange: tc.ghesExchange}).Run(context.Background())
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.Nil(t, err)
+			var data Output
+			assert.Nil(t, server.Events()[0].DataAs(&data))
+			assert.Equal(t, GithubProvider, data.ProviderInfo.Provider)
+			assert.Equal(t, tc.serverUrl+"/SrimanPadmanabanCB/gha-action", server.Events()[0].Source())
+		})
+	}
+
+	t.Run("Malformed token", func(t *testing.T) {
+		assert.Nil(t, validateTokenIssuer(&Config{}, "mock-oidc-token"))
+		assert.Equal(t, "malformed OIDC token", validateTokenIssuer(&Config{GhesTokenExchange: true}, "mock-oidc-token").Error())
+	})
+}
@@ -0,0 +1,274 @@
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// actionsArtifactRegexp matches the URL of a workflow artifact,
+// <server>/<owner>/<repo>/actions/runs/<run_id>/artifacts/<artifact_id>.
+var actionsArtifactRegexp = regexp.MustCompile(`^https?://[^/]+/([^/]+/[^/]+)/actions/runs/(\d+)/artifacts/(\d+)$`)
+
+type workflowRun struct {
+	Id           int64     `json:"id"`
+	RunAttempt   int       `json:"run_attempt"`
+	Status       string    `json:"status"`
+	RunStartedAt time.Time `json:"run_started_at"`
+}
+
+type actionsArtifact struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Expired     bool   `json:"expired"`
+	WorkflowRun struct {
+		Id int64 `json:"id"`
+	} `json:"workflow_run"`
+}
+
+type packageVersion struct {
+	Id        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Metadata  struct {
+		Container struct {
+			Tags []string `json:"tags"`
+		} `json:"container"`
+	} `json:"metadata"`
+}
+
+// verifyWorkflowRun checks with the GitHub API that the workflow run attempt
+// registering the artifact is in progress and, for workflow artifacts and
+// GHCR images, that the artifact was published by that run.
+func verifyWorkflowRun(ctx context.Context, config *Config) (err error) {
+	ctx, span := startSpan(ctx, StepVerifyRun)
+	defer func() { endSpan(span, err) }()
+
+	logger := stepLogger(StepVerifyRun)
+	run := config.workflowRun
+	if run == nil {
+		run, err = getWorkflowRun(ctx, config)
+		if err != nil {
+			return err
+		}
+	}
+
+	if match := actionsArtifactRegexp.FindStringSubmatch(config.ArtifactUrl); match != nil {
+		err = verifyActionsArtifact(ctx, config, match[1], match[2], match[3])
+	} else if reference := ghcrReference(config.ArtifactUrl); reference != nil {
+		err = verifyPackageVersion(ctx, config, *run, reference)
+	} else {
+		logger.Debug("Artifact is not published to GitHub, only the workflow run was verified", "url", config.ArtifactUrl)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(attribute.Bool("artifact.published_by_run", true))
+	logger.Info("Artifact verified as published by the workflow run", "url", config.ArtifactUrl)
+	return nil
+}
+
+// getWorkflowRun returns the workflow run attempt registering the artifacts,
+// which must be in progress.
+func getWorkflowRun(ctx context.Context, config *Config) (*workflowRun, error) {
+	if config.GithubToken == "" {
+		return nil, errors.New(GithubToken + " is required to verify the workflow run")
+	}
+	run := &workflowRun{}
+	err := githubGet(ctx, config, fmt.Sprintf("repos/%s/actions/runs/%s/attempts/%s", config.GhaRepository, config.GhaRunId, config.GhaRunAttempt), run)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify workflow run %s attempt %s: %w", config.GhaRunId, config.GhaRunAttempt, err)
+	}
+	if run.Status != "in_progress" {
+		return nil, fmt.Errorf("workflow run %s attempt %s is %s, expected in_progress", config.GhaRunId, config.GhaRunAttempt, run.Status)
+	}
+	stepLogger(StepVerifyRun).Debug("Workflow run verified", "run_id", run.Id, "run_attempt", run.RunAttempt, "started_at", run.RunStartedAt)
+	return run, nil
+}
+
+func verifyActionsArtifact(ctx context.Context, config *Config, repository, runId, artifactId string) error {
+	if repository != config.GhaRepository || runId != config.GhaRunId {
+		return fmt.Errorf("artifact %s does not belong to workflow run %s of %s", config.ArtifactUrl, config.GhaRunId, config.GhaRepository)
+	}
+	var artifact actionsArtifact
+	err := githubGet(ctx, config, fmt.Sprintf("repos/%s/actions/artifacts/%s", repository, artifactId), &artifact)
+	if err != nil {
+		return fmt.Errorf("failed to verify workflow artifact %s: %w", artifactId, err)
+	}
+	if fmt.Sprint(artifact.WorkflowRun.Id) != config.GhaRunId {
+		return fmt.Errorf("workflow artifact %s was uploaded by run %d, not %s", artifact.Name, artifact.WorkflowRun.Id, config.GhaRunId)
+	}
+	if artifact.Expired {
+		return fmt.Errorf("workflow artifact %s has expired", artifact.Name)
+	}
+	return nil
+}
+
+// verifyPackageVersion checks that the GHCR image version exists and was
+// created after the run attempt started, as the package API does not link
+// versions to the run that pushed them. Without a digest, any version with
+// the tag matches, including one pushed by a concurrent run.
+func verifyPackageVersion(ctx context.Context, config *Config, run workflowRun, reference *ArtifactReference) error {
+	owner, name, _ := strings.Cut(reference.Name, "/")
+	packagePath := fmt.Sprintf("%s/packages/container/%s/versions?per_page=100", owner, url.PathEscape(name))
+
+	versions, err := githubList[packageVersion](ctx, config, "orgs/"+packagePath)
+	var githubErr *githubError
+	if errors.As(err, &githubErr) && githubErr.StatusCode == http.StatusNotFound {
+		versions, err = githubList[packageVersion](ctx, config, "users/"+packagePath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify package %s: %w", reference.Name, err)
+	}
+
+	tag := firstNonEmpty(reference.Version, config.ArtifactVersion)
+	digest := firstNonEmpty(reference.Digest, config.ArtifactDigest)
+	for _, version := range versions {
+		if (digest != "" && version.Name == digest) || (digest == "" && slices.Contains(version.Metadata.Container.Tags, tag)) {
+			if version.CreatedAt.Before(run.RunStartedAt) {
+				return fmt.Errorf("package version %s of %s was published at %s, before workflow run %s attempt %s started",
+					version.Name, reference.Name, version.CreatedAt.Format(time.RFC3339), config.GhaRunId, config.GhaRunAttempt)
+			}
+			if digest == "" {
+				stepLogger(StepVerifyRun).Warn("Package version only verified by tag and creation time, set the digest to verify the image pushed by this run",
+					"package", reference.Name, "tag", tag, "version", version.Name)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("package %s has no version matching %s", reference.Name, firstNonEmpty(digest, tag))
+}
+
+// ghcrReference returns the image reference of a GHCR artifact URL, with or
+// without a docker://, oci:// or https:// scheme, and nil for other URLs.
+func ghcrReference(artifactUrl string) *ArtifactReference {
+	reference, err := ParseDockerReference(strings.TrimPrefix(artifactUrl, "https://"))
+	if err != nil || !strings.HasPrefix(reference.Url, GhcrRegistry+"/") {
+		return nil
+	}
+	return reference
+}
+
+type githubError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *githubError) Error() string {
+	return fmt.Sprintf("GitHub API - %s : %s", e.Status, e.Message)
+}
+
+func githubGet(ctx context.Context, config *Config, path string, value any) error {
+	resp, err := githubRequest(ctx, config, path, GithubJsonMediaType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	return json.Unmarshal(body, value)
+}
+
+// githubList returns all the pages of a GitHub API list, following the next
+// links of the responses.
+func githubList[T any](ctx context.Context, config *Config, path string) ([]T, error) {
+	apiUrl := getGithubApiUrl(config)
+	requestUrl := apiUrl + "/" + path
+	var items []T
+	for requestUrl != "" {
+		resp, err := githubRequest(ctx, config, requestUrl, GithubJsonMediaType)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading response body: %w", err)
+		}
+		var page []T
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+
+		requestUrl = nextPageUrl(resp.Header.Get(LinkHeaderKey))
+		if requestUrl != "" && !strings.HasPrefix(requestUrl, apiUrl+"/") {
+			return nil, fmt.Errorf("next page %s is not on the GitHub API %s", requestUrl, apiUrl)
+		}
+	}
+	return items, nil
+}
+
+func nextPageUrl(link string) string {
+	for _, value := range strings.Split(link, ",") {
+		target, params, _ := strings.Cut(strings.TrimSpace(value), ";")
+		if slices.Contains(strings.Fields(strings.ReplaceAll(params, ";", " ")), `rel="next"`) {
+			return strings.Trim(target, "<>")
+		}
+	}
+	return ""
+}
+
+// githubRequest returns the successful response of a GitHub API request to a
+// path of the API or an absolute URL. The caller closes the body.
+func githubRequest(ctx context.Context, config *Config, path string, accept string) (*http.Response, error) {
+	requestUrl := path
+	if !strings.Contains(path, "://") {
+		requestUrl = getGithubApiUrl(config) + "/" + path
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(AcceptHeaderKey, accept)
+	req.Header.Set(AuthorizationHeaderKey, Bearer+config.GithubToken)
+	req.Header.Set(GithubApiVersionHeaderKey, GithubApiVersion)
+
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	var errorResponse struct {
+		Message string `json:"message"`
+	}
+	message := string(body)
+	if json.Unmarshal(body, &errorResponse) == nil && errorResponse.Message != "" {
+		message = errorResponse.Message
+	}
+	return nil, &githubError{StatusCode: resp.StatusCode, Status: resp.Status, Message: message}
+}
+
+func getGithubApiUrl(config *Config) string {
+	if config.GithubApiUrl != "" {
+		return strings.TrimSuffix(config.GithubApiUrl, "/")
+	}
+	if host := getGheComHost(config); host != "" {
+		return "https://api." + host
+	}
+	if serverUrl := getServerUrl(config); serverUrl != DefaultGithubServerUrl {
+		return serverUrl + "/api/v3"
+	}
+	return DefaultGithubApiUrl
+}
@@ -0,0 +1,112 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyWorkflowRun(t *testing.T) {
+	started := time.Now().Add(-time.Minute).UTC()
+	repository := "SrimanPadmanabanCB/gha-action"
+	githubConfig := platformtest.Config{
+		GithubToken: "github-token",
+		WorkflowRuns: []platformtest.WorkflowRun{
+			{Repository: repository, Id: 123456789, Attempt: 1, Status: "in_progress", StartedAt: started},
+			{Repository: repository, Id: 123456789, Attempt: 2, Status: "completed", StartedAt: started},
+		},
+		ActionsArtifacts: []platformtest.ActionsArtifact{
+			{Repository: repository, Id: 42, Name: "dist", RunId: 123456789},
+			{Repository: repository, Id: 43, Name: "other", RunId: 987654321},
+		},
+		PackageVersions: []platformtest.PackageVersion{
+			{Owner: "owner", Package: "app", Id: 1, Digest: testDigest, Tags: []string{"1.0.0"}, CreatedAt: started.Add(30 * time.Second)},
+			{Owner: "owner", Package: "app", Id: 2, Digest: "sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", Tags: []string{"0.9.0"}, CreatedAt: started.Add(-time.Hour)},
+		},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		attempt string
+		url     string
+		version string
+		err     string
+	}{
+		{"Other artifact", "1", "https://test.com", "1.0.0", ""},
+		{"Completed run", "2", "https://test.com", "1.0.0", "workflow run 123456789 attempt 2 is completed, expected in_progress"},
+		{"Unknown run", "3", "https://test.com", "1.0.0", "failed to verify workflow run 123456789 attempt 3: GitHub API - 404 Not Found : Not Found"},
+		{"Workflow artifact", "1", "https://github.com/" + repository + "/actions/runs/123456789/artifacts/42", "1.0.0", ""},
+		{"Workflow artifact of another run", "1", "https://github.com/" + repository + "/actions/runs/987654321/artifacts/43", "1.0.0",
+			"artifact https://github.com/" + repository + "/actions/runs/987654321/artifacts/43 does not belong to workflow run 123456789 of " + repository},
+		{"Container image", "1", "ghcr.io/owner/app:1.0.0", "1.0.0", ""},
+		{"Container image by digest", "1", "ghcr.io/owner/app@" + testDigest, "1.0.0", ""},
+		{"Container image published before the run", "1", "ghcr.io/owner/app:0.9.0", "0.9.0",
+			"package version sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef of owner/app was published at " + started.Add(-time.Hour).Format(time.RFC3339) + ", before workflow run 123456789 attempt 1 started"},
+		{"Unknown image tag", "1", "ghcr.io/owner/app:2.0.0", "2.0.0", "package owner/app has no version matching 2.0.0"},
+		{"Container image with docker scheme", "1", "docker://ghcr.io/owner/app:2.0.0", "2.0.0", "package owner/app has no version matching 2.0.0"},
+		{"Container image with https scheme", "1", "https://ghcr.io/owner/app:2.0.0", "2.0.0", "package owner/app has no version matching 2.0.0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := testserver.New(t, githubConfig)
+			setTestEnv(t, server)
+			t.Setenv(GithubRunAttempt, tc.attempt)
+			t.Setenv(ArtifactUrl, tc.url)
+			t.Setenv(ArtifactVersion, tc.version)
+			config := Config{VerifyRun: true, GithubToken: "github-token", GithubApiUrl: server.GithubApiUrl()}
+			_, err := config.Run(context.Background())
+			if tc.err == "" {
+				assert.Nil(t, err)
+				assert.Len(t, server.Events(), 1)
+			} else {
+				assert.Equal(t, tc.err, err.Error())
+				assert.Empty(t, server.Events())
+			}
+		})
+	}
+
+	t.Run("Container image on a later page", func(t *testing.T) {
+		pagedConfig := githubConfig
+		pagedConfig.PackageVersions = nil
+		for i := range 150 {
+			pagedConfig.PackageVersions = append(pagedConfig.PackageVersions, platformtest.PackageVersion{
+				Owner: "owner", Package: "app", Id: int64(i + 10), Digest: fmt.Sprintf("sha256:%064x", i), Tags: []string{fmt.Sprintf("0.0.%d", i)}, CreatedAt: started.Add(time.Second),
+			})
+		}
+		server := testserver.New(t, pagedConfig)
+		setTestEnv(t, server)
+		t.Setenv(ArtifactUrl, "ghcr.io/owner/app:0.0.149")
+		t.Setenv(ArtifactVersion, "0.0.149")
+		config := Config{VerifyRun: true, GithubToken: "github-token", GithubApiUrl: server.GithubApiUrl()}
+		_, err := config.Run(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, server.Events(), 1)
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		server := testserver.New(t, githubConfig)
+		setTestEnv(t, server)
+		config := Config{VerifyRun: true, GithubApiUrl: server.GithubApiUrl()}
+		_, err := config.Run(context.Background())
+		assert.Equal(t, "GITHUB_TOKEN is required to verify the workflow run", err.Error())
+	})
+
+	t.Run("Bad credentials", func(t *testing.T) {
+		server := testserver.New(t, githubConfig)
+		setTestEnv(t, server)
+		config := Config{VerifyRun: true, GithubToken: "other", GithubApiUrl: server.GithubApiUrl()}
+		_, err := config.Run(context.Background())
+		assert.Equal(t, "failed to verify workflow run 123456789 attempt 1: GitHub API - 401 Unauthorized : Bad credentials", err.Error())
+	})
+}
+
+func TestGetGithubApiUrl(t *testing.T) {
+	assert.Equal(t, DefaultGithubApiUrl, getGithubApiUrl(&Config{}))
+	assert.Equal(t, DefaultGithubApiUrl, getGithubApiUrl(&Config{GhaServerUrl: "https://github.com"}))
+	assert.Equal(t, "https://github.example.com/api/v3", getGithubApiUrl(&Config{GhaServerUrl: "https://github.example.com/"}))
+	assert.Equal(t, "http://localhost:8080", getGithubApiUrl(&Config{GithubApiUrl: "http://localhost:8080/"}))
+}
@@ -0,0 +1,230 @@
+package artifacts
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InferredValue records a Config field filled in by inference and where it came from.
+type InferredValue struct {
+	Field  string
+	Value  string
+	Source string
+}
+
+type buildFileInference func(dir string) (*ArtifactReference, string, error)
+
+// buildFileInferences are tried in order; the first build file found is used.
+var buildFileInferences = []struct {
+	file  string
+	infer buildFileInference
+}{
+	{"pom.xml", inferFromPom},
+	{"package.json", inferFromPackageJson},
+	{"Chart.yaml", inferFromChart},
+	{"go.mod", inferFromGoMod},
+}
+
+// inferArtifactInfo fills the artifact fields that were not provided from a
+// docker reference in ArtifactUrl or from the build files in InferDir.
+func inferArtifactInfo(config *Config) ([]InferredValue, error) {
+	for env, field := range map[string]*string{
+		ArtifactName:    &config.ArtifactName,
+		ArtifactUrl:     &config.ArtifactUrl,
+		ArtifactVersion: &config.ArtifactVersion,
+	} {
+		if *field == "" {
+			*field = os.Getenv(env)
+		}
+	}
+
+	var inferred []InferredValue
+	fill := func(field *string, name string, value string, source string) {
+		if *field == "" && value != "" {
+			*field = value
+			inferred = append(inferred, InferredValue{Field: name, Value: value, Source: source})
+		}
+	}
+
+	if config.ArtifactUrl != "" && isDockerType(config.ArtifactType) {
+		if reference, err := ParseDockerReference(config.ArtifactUrl); err == nil && (reference.Version != "" || reference.Digest != "") {
+			source := "docker reference " + config.ArtifactUrl
+			fill(&config.ArtifactName, ArtifactName, path.Base(reference.Name), source)
+			fill(&config.ArtifactVersion, ArtifactVersion, reference.Version, source)
+			fill(&config.ArtifactDigest, ArtifactDigest, reference.Digest, source)
+			fill(&config.ArtifactType, ArtifactType, "docker", source)
+			// A reference by digest has no tag, so the version may come
+			// from the build files.
+			if config.ArtifactVersion != "" {
+				return inferred, nil
+			}
+		}
+	}
+
+	dir := config.InferDir
+	if dir == "" {
+		dir = "."
+	}
+	for _, buildFile := range buildFileInferences {
+		source := filepath.Join(dir, buildFile.file)
+		if _, err := os.Stat(source); err != nil {
+			continue
+		}
+		reference, artifactType, err := buildFile.infer(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to infer artifact from %s: %w", source, err)
+		}
+		fill(&config.ArtifactName, ArtifactName, reference.Name, source)
+		fill(&config.ArtifactVersion, ArtifactVersion, reference.Version, source)
+		fill(&config.ArtifactUrl, ArtifactUrl, reference.Url, source)
+		fill(&config.ArtifactType, ArtifactType, artifactType, source)
+		if config.ArtifactUrl == "" && artifactType == "helm" {
+			return nil, fmt.Errorf("cannot infer the url of helm chart %s from %s, set the url to the chart repository or OCI reference, e.g. oci://ghcr.io/<owner>/charts/%s:%s",
+				reference.Name, source, reference.Name, reference.Version)
+		}
+		return inferred, nil
+	}
+	return inferred, nil
+}
+
+func isDockerType(artifactType string) bool {
+	switch strings.ToLower(artifactType) {
+	case "", "docker", "oci", "container":
+		return true
+	}
+	return false
+}
+
+func inferFromPom(dir string) (*ArtifactReference, string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "pom.xml"))
+	if err != nil {
+		return nil, "", err
+	}
+	var pom struct {
+		GroupId    string `xml:"groupId"`
+		ArtifactId string `xml:"artifactId"`
+		Version    string `xml:"version"`
+		Parent     struct {
+			GroupId string `xml:"groupId"`
+			Version string `xml:"version"`
+		} `xml:"parent"`
+		Properties struct {
+			Entries []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:"properties"`
+	}
+	if err := xml.Unmarshal(data, &pom); err != nil {
+		return nil, "", err
+	}
+
+	groupId := firstNonEmpty(pom.GroupId, pom.Parent.GroupId)
+	version := firstNonEmpty(pom.Version, pom.Parent.Version)
+	// Resolve a version given as a single property such as ${revision}
+	if property, found := strings.CutPrefix(version, "${"); found {
+		version = ""
+		for _, entry := range pom.Properties.Entries {
+			if entry.XMLName.Local+"}" == property {
+				version = strings.TrimSpace(entry.Value)
+			}
+		}
+	}
+
+	reference := &ArtifactReference{Name: pom.ArtifactId, Version: version}
+	if groupId != "" && pom.ArtifactId != "" && version != "" {
+		reference.Url = groupId + ":" + pom.ArtifactId + ":" + version
+	}
+	return reference, "maven", nil
+}
+
+func inferFromPackageJson(dir string) (*ArtifactReference, string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil, "", err
+	}
+	var pkg struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, "", err
+	}
+	reference := &ArtifactReference{Name: pkg.Name, Version: pkg.Version}
+	if pkg.Name != "" && pkg.Version != "" {
+		reference.Url = pkg.Name + "@" + pkg.Version
+	}
+	return reference, "npm", nil
+}
+
+func inferFromChart(dir string) (*ArtifactReference, string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "Chart.yaml"))
+	if err != nil {
+		return nil, "", err
+	}
+	var chart struct {
+		Name    string `yaml:"name"`
+		Version string `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(data, &chart); err != nil {
+		return nil, "", err
+	}
+	return &ArtifactReference{Name: chart.Name, Version: chart.Version}, "helm", nil
+}
+
+func inferFromGoMod(dir string) (*ArtifactReference, string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, "", err
+	}
+	var module string
+	for _, line := range strings.Split(string(data), "\n") {
+		if name, found := strings.CutPrefix(strings.TrimSpace(line), "module "); found {
+			module = strings.Trim(strings.TrimSpace(name), `"`)
+			break
+		}
+	}
+	if module == "" {
+		return nil, "", fmt.Errorf("no module directive found")
+	}
+
+	reference := &ArtifactReference{Name: module, Version: gitDescribe(dir)}
+	if reference.Version != "" {
+		reference.Url = "https://pkg.go.dev/" + module + "@" + reference.Version
+	}
+	return reference, "go", nil
+}
+
+// gitDescribe returns the tag pointing at HEAD, or the nearest tag with the
+// number of commits since and the abbreviated commit when HEAD is not tagged.
+func gitDescribe(dir string) string {
+	for _, args := range [][]string{
+		{"describe", "--tags", "--exact-match", "HEAD"},
+		{"describe", "--tags"},
+	} {
+		command := exec.Command("git", args...)
+		command.Dir = dir
+		out, err := command.Output()
+		if err == nil {
+			return strings.TrimSpace(string(out))
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
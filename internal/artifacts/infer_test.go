@@ -0,0 +1,134 @@
+package artifacts
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeBuildFile(t *testing.T, dir string, name string, content string) {
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+}
+
+func TestInferArtifactInfo(t *testing.T) {
+	t.Setenv(ArtifactName, "")
+	t.Setenv(ArtifactUrl, "")
+	t.Setenv(ArtifactVersion, "")
+
+	t.Run("Docker reference", func(t *testing.T) {
+		config := Config{ArtifactUrl: "ghcr.io/owner/app:1.2.3@" + testDigest}
+		inferred, err := inferArtifactInfo(&config)
+		assert.Nil(t, err)
+		assert.Equal(t, "app", config.ArtifactName)
+		assert.Equal(t, "1.2.3", config.ArtifactVersion)
+		assert.Equal(t, testDigest, config.ArtifactDigest)
+		assert.Equal(t, "docker", config.ArtifactType)
+		assert.Len(t, inferred, 4)
+		assert.Equal(t, "docker reference ghcr.io/owner/app:1.2.3@"+testDigest, inferred[0].Source)
+	})
+
+	t.Run("pom.xml", func(t *testing.T) {
+		dir := t.TempDir()
+		writeBuildFile(t, dir, "pom.xml", `<project>
+  <parent><groupId>com.example</groupId><version>1.0.0</version></parent>
+  <artifactId>app</artifactId>
+  <version>${revision}</version>
+  <properties><revision>2.0.0</revision></properties>
+</project>`)
+		config := Config{InferDir: dir}
+		inferred, err := inferArtifactInfo(&config)
+		assert.Nil(t, err)
+		assert.Equal(t, "app", config.ArtifactName)
+		assert.Equal(t, "2.0.0", config.ArtifactVersion)
+		assert.Equal(t, "com.example:app:2.0.0", config.ArtifactUrl)
+		assert.Equal(t, "maven", config.ArtifactType)
+		assert.Equal(t, filepath.Join(dir, "pom.xml"), inferred[0].Source)
+	})
+
+	t.Run("package.json keeps provided values", func(t *testing.T) {
+		dir := t.TempDir()
+		writeBuildFile(t, dir, "package.json", `{"name": "@scope/pkg", "version": "1.0.0"}`)
+		t.Setenv(ArtifactName, "custom")
+		config := Config{InferDir: dir, ArtifactType: "generic"}
+		inferred, err := inferArtifactInfo(&config)
+		assert.Nil(t, err)
+		assert.Equal(t, "custom", config.ArtifactName)
+		assert.Equal(t, "1.0.0", config.ArtifactVersion)
+		assert.Equal(t, "@scope/pkg@1.0.0", config.ArtifactUrl)
+		assert.Equal(t, "generic", config.ArtifactType)
+		assert.Len(t, inferred, 2)
+	})
+
+	t.Run("Chart.yaml", func(t *testing.T) {
+		dir := t.TempDir()
+		writeBuildFile(t, dir, "Chart.yaml", "apiVersion: v2\nname: my-chart\nversion: 0.1.0\n")
+		config := Config{InferDir: dir, ArtifactUrl: "oci://ghcr.io/owner/charts/my-chart:0.1.0", ArtifactType: "helm"}
+		_, err := inferArtifactInfo(&config)
+		assert.Nil(t, err)
+		assert.Equal(t, "my-chart", config.ArtifactName)
+		assert.Equal(t, "0.1.0", config.ArtifactVersion)
+		assert.Equal(t, "oci://ghcr.io/owner/charts/my-chart:0.1.0", config.ArtifactUrl)
+		assert.Equal(t, "helm", config.ArtifactType)
+
+		config = Config{InferDir: dir}
+		_, err = inferArtifactInfo(&config)
+		assert.EqualError(t, err, "cannot infer the url of helm chart my-chart from "+filepath.Join(dir, "Chart.yaml")+
+			", set the url to the chart repository or OCI reference, e.g. oci://ghcr.io/<owner>/charts/my-chart:0.1.0")
+	})
+
+	t.Run("Docker reference by digest", func(t *testing.T) {
+		dir := t.TempDir()
+		writeBuildFile(t, dir, "package.json", `{"name": "app", "version": "3.0.0"}`)
+		config := Config{InferDir: dir, ArtifactUrl: "ghcr.io/owner/app@" + testDigest}
+		_, err := inferArtifactInfo(&config)
+		assert.Nil(t, err)
+		assert.Equal(t, "app", config.ArtifactName)
+		assert.Equal(t, "3.0.0", config.ArtifactVersion)
+		assert.Equal(t, testDigest, config.ArtifactDigest)
+		assert.Equal(t, "ghcr.io/owner/app@"+testDigest, config.ArtifactUrl)
+		assert.Equal(t, "docker", config.ArtifactType)
+	})
+
+	t.Run("go.mod and git tag", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git is not installed")
+		}
+		dir := t.TempDir()
+		writeBuildFile(t, dir, "go.mod", "module example.com/app\n\ngo 1.23\n")
+		for _, args := range [][]string{
+			{"init", "-q"},
+			{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+			{"tag", "v1.4.0"},
+		} {
+			command := exec.Command("git", args...)
+			command.Dir = dir
+			assert.Nil(t, command.Run())
+		}
+		config := Config{InferDir: dir}
+		_, err := inferArtifactInfo(&config)
+		assert.Nil(t, err)
+		assert.Equal(t, "example.com/app", config.ArtifactName)
+		assert.Equal(t, "v1.4.0", config.ArtifactVersion)
+		assert.Equal(t, "https://pkg.go.dev/example.com/app@v1.4.0", config.ArtifactUrl)
+		assert.Equal(t, "go", config.ArtifactType)
+	})
+
+	t.Run("Invalid build file", func(t *testing.T) {
+		dir := t.TempDir()
+		writeBuildFile(t, dir, "package.json", `{`)
+		config := Config{InferDir: dir}
+		_, err := inferArtifactInfo(&config)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "failed to infer artifact from")
+	})
+
+	t.Run("Nothing to infer", func(t *testing.T) {
+		config := Config{InferDir: t.TempDir()}
+		inferred, err := inferArtifactInfo(&config)
+		assert.Nil(t, err)
+		assert.Empty(t, inferred)
+	})
+}
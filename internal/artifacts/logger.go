@@ -0,0 +1,57 @@
+package artifacts
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// LogOptions selects the format and level of the default slog logger.
+type LogOptions struct {
+	Format  string
+	Level   string
+	Verbose bool
+	Quiet   bool
+}
+
+// ConfigureLogger installs a leveled text or JSON slog logger writing to w as
+// the default logger. Verbose enables debug logs, Quiet only keeps errors and
+// otherwise Level applies. RUNNER_DEBUG=1 enables debug logs unless one of them
+// is set explicitly.
+func ConfigureLogger(w io.Writer, options LogOptions) error {
+	level := slog.LevelInfo
+	if options.Level != "" {
+		if err := level.UnmarshalText([]byte(options.Level)); err != nil {
+			return fmt.Errorf("invalid log level %q, expected debug, info, warn or error", options.Level)
+		}
+	}
+	switch {
+	case options.Verbose && options.Quiet:
+		return fmt.Errorf("--verbose and --quiet are mutually exclusive")
+	case options.Verbose:
+		level = slog.LevelDebug
+	case options.Quiet:
+		level = slog.LevelError
+	case options.Level == "" && os.Getenv(RunnerDebug) == "1":
+		level = slog.LevelDebug
+	}
+
+	handlerOptions := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(options.Format) {
+	case "", LogFormatText:
+		handler = slog.NewTextHandler(w, handlerOptions)
+	case LogFormatJson:
+		handler = slog.NewJSONHandler(w, handlerOptions)
+	default:
+		return fmt.Errorf("invalid log format %q, expected text or json", options.Format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+func stepLogger(step string) *slog.Logger {
+	return slog.With(LogStep, step)
+}
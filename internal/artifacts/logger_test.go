@@ -0,0 +1,75 @@
+package artifacts
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigureLogger(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+	t.Setenv(RunnerDebug, "")
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Nil(t, ConfigureLogger(&buf, LogOptions{Format: "json"}))
+		stepLogger(StepSendEvent).Info("CloudEvent sent successfully", LogEventId, "id", LogHttpStatus, 200)
+		slog.Debug("hidden")
+
+		var entry map[string]any
+		assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "INFO", entry["level"])
+		assert.Equal(t, StepSendEvent, entry[LogStep])
+		assert.Equal(t, "id", entry[LogEventId])
+		assert.Equal(t, float64(200), entry[LogHttpStatus])
+	})
+
+	t.Run("Levels", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Nil(t, ConfigureLogger(&buf, LogOptions{Level: "warn"}))
+		slog.Info("hidden")
+		slog.Warn("shown")
+		assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+		assert.Contains(t, buf.String(), "level=WARN msg=shown")
+
+		buf.Reset()
+		assert.Nil(t, ConfigureLogger(&buf, LogOptions{Quiet: true}))
+		slog.Warn("hidden")
+		assert.Empty(t, buf.String())
+
+		assert.Nil(t, ConfigureLogger(&buf, LogOptions{Verbose: true, Level: "error"}))
+		slog.Debug("shown")
+		assert.Contains(t, buf.String(), "level=DEBUG msg=shown")
+	})
+
+	t.Run("Runner debug", func(t *testing.T) {
+		t.Setenv(RunnerDebug, "1")
+		var buf bytes.Buffer
+		assert.Nil(t, ConfigureLogger(&buf, LogOptions{}))
+		slog.Debug("shown")
+		assert.Contains(t, buf.String(), "level=DEBUG msg=shown")
+
+		buf.Reset()
+		assert.Nil(t, ConfigureLogger(&buf, LogOptions{Quiet: true}))
+		slog.Warn("hidden")
+		assert.Empty(t, buf.String())
+
+		assert.Nil(t, ConfigureLogger(&buf, LogOptions{Level: "error"}))
+		slog.Warn("hidden")
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("Invalid options", func(t *testing.T) {
+		err := ConfigureLogger(&bytes.Buffer{}, LogOptions{Format: "xml"})
+		assert.Equal(t, `invalid log format "xml", expected text or json`, err.Error())
+		err = ConfigureLogger(&bytes.Buffer{}, LogOptions{Level: "trace"})
+		assert.Equal(t, `invalid log level "trace", expected debug, info, warn or error`, err.Error())
+		err = ConfigureLogger(&bytes.Buffer{}, LogOptions{Verbose: true, Quiet: true})
+		assert.Equal(t, "--verbose and --quiet are mutually exclusive", err.Error())
+	})
+}
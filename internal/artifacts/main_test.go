@@ -0,0 +1,12 @@
+package artifacts
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// On a runner the state file would turn repeated test registrations into no-ops.
+	os.Unsetenv(RunnerTemp)
+	os.Exit(m.Run())
+}
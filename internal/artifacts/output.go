@@ -7,17 +7,31 @@ type ArtifactInfo struct {
 	ArtifactType    string `json:"artifact_type,omitempty"`
 	ArtifactDigest  string `json:"artifact_digest,omitempty"`
 	ArtifactLabel   string `json:"artifact_label,omitempty"`
+	ArtifactSize    int64  `json:"artifact_size,omitempty"`
+	ParentDigest    string `json:"parent_digest,omitempty"`
+	Platform        string `json:"platform,omitempty"`
 }
 
 type ProviderInfo struct {
-	RunId      string `json:"run_id,omitempty"`
-	RunAttempt string `json:"run_attempt,omitempty"`
-	RunNumber  string `json:"run_number,omitempty"`
-	JobName    string `json:"job_name,omitempty"`
-	Provider   string `json:"provider,omitempty"`
+	RunId             string            `json:"run_id,omitempty"`
+	RunAttempt        string            `json:"run_attempt,omitempty"`
+	RunNumber         string            `json:"run_number,omitempty"`
+	JobName           string            `json:"job_name,omitempty"`
+	Provider          string            `json:"provider,omitempty"`
+	RunnerName        string            `json:"runner_name,omitempty"`
+	RunnerOs          string            `json:"runner_os,omitempty"`
+	RunnerArch        string            `json:"runner_arch,omitempty"`
+	RunnerEnvironment string            `json:"runner_environment,omitempty"`
+	ImageOs           string            `json:"image_os,omitempty"`
+	ImageVersion      string            `json:"image_version,omitempty"`
+	Toolchains        map[string]string `json:"toolchains,omitempty"`
 }
 
 type Output struct {
-	ProviderInfo ProviderInfo `json:"provider_info"`
-	ArtifactInfo ArtifactInfo `json:"artifact_info"`
+	ProviderInfo  ProviderInfo    `json:"provider_info"`
+	ArtifactInfo  ArtifactInfo    `json:"artifact_info"`
+	SourceInfo    *SourceInfo     `json:"source_info,omitempty"`
+	Relationships *Relationships  `json:"relationships,omitempty"`
+	Release       *ReleaseInfo    `json:"release,omitempty"`
+	Provenance    *ProvenanceInfo `json:"provenance,omitempty"`
 }
@@ -0,0 +1,71 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// registerPlatforms registers an image index and each of its platform images.
+// The platform images are registered with their own digest and the digest of
+// the index as parent. A single-platform image is registered with the digest
+// of its manifest. When a platform fails, the registrations so far are
+// returned with the error.
+func registerPlatforms(ctx context.Context, config *Config) (result *RegistrationResult, err error) {
+	logger := stepLogger(StepPlatforms)
+	reference, err := ParseDockerReference(config.ArtifactUrl)
+	if err != nil {
+		return nil, fmt.Errorf("registering platforms requires an image reference, got %q: %w", config.ArtifactUrl, err)
+	}
+	index, err := fetchImageIndex(ctx, config, reference)
+	if err != nil {
+		return nil, err
+	}
+	if !index.IsIndex() {
+		logger.Info("Image is not an image index, registering it as a single artifact", "url", config.ArtifactUrl, "media_type", index.MediaType)
+	}
+	if config.ArtifactDigest != "" && !strings.EqualFold(config.ArtifactDigest, index.Digest) {
+		return nil, fmt.Errorf("artifact digest %q does not match digest %q of image %s", config.ArtifactDigest, index.Digest, config.ArtifactUrl)
+	}
+	config.ArtifactDigest = index.Digest
+	if !index.IsIndex() {
+		return register(ctx, config)
+	}
+
+	// The index and its platforms share the access token.
+	indexConfig := *config
+	if indexConfig.token == nil {
+		indexConfig.token = &sharedToken{}
+	}
+	result, err = register(ctx, &indexConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, _, _ := strings.Cut(reference.Url, "/")
+	repository := registry + "/" + reference.Name
+	for _, manifest := range index.Platforms() {
+		platformConfig := indexConfig
+		platformConfig.ArtifactUrl = repository + "@" + manifest.Digest
+		platformConfig.ArtifactDigest = manifest.Digest
+		platformConfig.ParentDigest = index.Digest
+		platformConfig.Platform = manifest.Platform.String()
+		platformConfig.Relationships = nil
+
+		platformResult, err := registerPlatform(ctx, &platformConfig)
+		if err != nil {
+			return result, fmt.Errorf("failed to register platform %s of %s: %w", platformConfig.Platform, config.ArtifactUrl, err)
+		}
+		result.Platforms = append(result.Platforms, platformResult)
+	}
+	logger.Info("Registered image index and its platforms", "url", config.ArtifactUrl, "digest", index.Digest, "platforms", len(result.Platforms))
+	return result, nil
+}
+
+func registerPlatform(ctx context.Context, config *Config) (result *RegistrationResult, err error) {
+	ctx, span := startSpan(ctx, StepPlatforms, attribute.String(AttributePlatform, config.Platform))
+	defer func() { endSpan(span, err) }()
+	return register(ctx, config)
+}
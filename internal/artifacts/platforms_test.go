@@ -0,0 +1,103 @@
+package artifacts
+
+import (
+	"context"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterPlatforms(t *testing.T) {
+	multiPlatform := platformtest.Image{Repository: "owner/app", Tag: "1.0.0", Platforms: []string{"linux/amd64", "linux/arm64/v8"}}
+	singlePlatform := platformtest.Image{Repository: "owner/tool", Tag: "1.0.0"}
+	config := platformtest.Config{Images: []platformtest.Image{multiPlatform, singlePlatform}}
+
+	t.Run("Image index", func(t *testing.T) {
+		server := testserver.New(t, config)
+		setTestEnv(t, server)
+		t.Setenv(ArtifactUrl, server.RegistryHost()+"/owner/app:1.0.0")
+
+		index := multiPlatform.Manifest()
+		result, err := (&Config{Platforms: true, ArtifactType: "docker"}).Run(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, result.Platforms, 2)
+		assert.Equal(t, "linux/amd64", result.Platforms[0].Platform)
+		assert.Equal(t, "linux/arm64/v8", result.Platforms[1].Platform)
+
+		artifacts := server.Artifacts()
+		assert.Len(t, artifacts, 3)
+		assert.Equal(t, 1, server.Requests(platformtest.TokenExchangeEndpoint))
+		assert.Equal(t, index.Digest, artifacts[0].ArtifactDigest)
+		assert.Equal(t, server.RegistryHost()+"/owner/app:1.0.0", artifacts[0].ArtifactUrl)
+
+		for i, platform := range []string{"linux/amd64", "linux/arm64/v8"} {
+			var data Output
+			assert.Nil(t, server.Events()[i+1].DataAs(&data))
+			digest := index.Platforms[platform]
+			assert.Equal(t, ArtifactInfo{
+				ArtifactName:    "testartifact",
+				ArtifactUrl:     server.RegistryHost() + "/owner/app@" + digest,
+				ArtifactVersion: "1.0.0",
+				ArtifactType:    "docker",
+				ArtifactDigest:  digest,
+				ParentDigest:    index.Digest,
+				Platform:        platform,
+			}, data.ArtifactInfo)
+		}
+	})
+
+	t.Run("Failed platform", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{Images: config.Images, Faults: map[string]platformtest.Fault{
+			platformtest.EventsEndpoint: {Status: http.StatusBadRequest, Body: `{"code": 400, "message": "invalid platform"}`, After: 2},
+		}})
+		setTestEnv(t, server)
+		t.Setenv(ArtifactUrl, server.RegistryHost()+"/owner/app:1.0.0")
+
+		result, err := (&Config{Platforms: true, ArtifactType: "docker"}).Run(context.Background())
+		assert.ErrorContains(t, err, "failed to register platform linux/arm64/v8 of "+server.RegistryHost()+"/owner/app:1.0.0")
+		assert.NotEmpty(t, result.EventId)
+		assert.Len(t, result.Platforms, 1)
+		assert.Equal(t, "linux/amd64", result.Platforms[0].Platform)
+	})
+
+	t.Run("Single platform image", func(t *testing.T) {
+		server := testserver.New(t, config)
+		setTestEnv(t, server)
+		t.Setenv(ArtifactUrl, server.RegistryHost()+"/owner/tool:1.0.0")
+
+		result, err := (&Config{Platforms: true, ArtifactType: "docker"}).Run(context.Background())
+		assert.Nil(t, err)
+		assert.Empty(t, result.Platforms)
+		assert.Len(t, server.Events(), 1)
+		assert.Equal(t, singlePlatform.Manifest().Digest, server.Artifacts()[0].ArtifactDigest)
+	})
+
+	t.Run("Digest mismatch", func(t *testing.T) {
+		server := testserver.New(t, config)
+		setTestEnv(t, server)
+		t.Setenv(ArtifactUrl, server.RegistryHost()+"/owner/app:1.0.0")
+
+		_, err := (&Config{Platforms: true, ArtifactType: "docker", ArtifactDigest: testDigest}).Run(context.Background())
+		assert.Equal(t, `artifact digest "`+testDigest+`" does not match digest "`+multiPlatform.Manifest().Digest+`" of image `+server.RegistryHost()+"/owner/app:1.0.0", err.Error())
+		assert.Empty(t, server.Events())
+	})
+
+	t.Run("Unknown image", func(t *testing.T) {
+		server := testserver.New(t, config)
+		setTestEnv(t, server)
+		t.Setenv(ArtifactUrl, server.RegistryHost()+"/owner/other:1.0.0")
+
+		_, err := (&Config{Platforms: true, ArtifactType: "docker"}).Run(context.Background())
+		assert.Contains(t, err.Error(), "error fetching manifest of "+server.RegistryHost()+"/owner/other:1.0.0 - 404 Not Found")
+	})
+}
+
+func TestRegistryBaseUrl(t *testing.T) {
+	assert.Equal(t, "https://registry-1.docker.io", registryBaseUrl("docker.io"))
+	assert.Equal(t, "https://ghcr.io", registryBaseUrl("ghcr.io"))
+	assert.Equal(t, "http://localhost:5000", registryBaseUrl("localhost:5000"))
+	assert.Equal(t, "http://127.0.0.1:8080", registryBaseUrl("127.0.0.1:8080"))
+}
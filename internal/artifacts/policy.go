@@ -0,0 +1,238 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	semverRegexp = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+	calverRegexp = regexp.MustCompile(`^(\d{4}|\d{2})\.(0?[1-9]|1[0-2])(?:\.(0?[1-9]|[12]\d|3[01]))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?$`)
+)
+
+// Policy declares the guardrails evaluated before an artifact is registered.
+// Empty rules are not evaluated.
+type Policy struct {
+	// AllowedHosts are the hosts or registries the artifact URL may point to,
+	// with * wildcards, e.g. ghcr.io or *.jfrog.io. Maven, npm and PyPI
+	// package specs name no host and are not checked.
+	AllowedHosts []string `yaml:"allowed-hosts" json:"allowed-hosts"`
+	// RequireDigest lists the artifact types that must have a digest, or * for
+	// all. Types are compared case-insensitively.
+	RequireDigest []string `yaml:"require-digest" json:"require-digest"`
+	// Version constrains the artifact version.
+	Version VersionPolicy `yaml:"version" json:"version"`
+	// RequiredLabels must all be present in the artifact labels.
+	RequiredLabels []string `yaml:"required-labels" json:"required-labels"`
+	// Branches are the refs of GITHUB_WORKFLOW_REF allowed to register, with *
+	// wildcards, e.g. refs/heads/main or refs/tags/v*. A * does not match /, so
+	// refs/heads/* allows refs/heads/main but not refs/heads/feature/x, which
+	// needs refs/heads/*/*.
+	Branches []string `yaml:"branches" json:"branches"`
+}
+
+// VersionPolicy constrains the artifact version.
+type VersionPolicy struct {
+	// Scheme is semver or calver.
+	Scheme string `yaml:"scheme" json:"scheme"`
+	// AllowPrerelease permits SemVer pre-release versions such as 1.0.0-rc.1.
+	AllowPrerelease bool `yaml:"allow-prerelease" json:"allow-prerelease"`
+	// Pattern is a regular expression the version must match.
+	Pattern string `yaml:"pattern" json:"pattern"`
+}
+
+// PolicyViolation is a rule of the policy the artifact does not satisfy.
+type PolicyViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError reports the violated rules of a policy.
+type PolicyError struct {
+	Path       string
+	Violations []PolicyViolation
+}
+
+func (e *PolicyError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "artifact violates %d rule(s) of policy %s", len(e.Violations), e.Path)
+	for _, violation := range e.Violations {
+		fmt.Fprintf(&sb, "\n  - %s: %s", violation.Rule, violation.Message)
+	}
+	return sb.String()
+}
+
+// LoadPolicy reads a YAML or JSON policy file.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	policy := &Policy{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", path, err)
+	}
+	switch policy.Version.Scheme {
+	case "", VersionSchemeSemver, VersionSchemeCalver:
+	default:
+		return nil, fmt.Errorf("invalid version scheme %q in policy %s, expected semver or calver", policy.Version.Scheme, path)
+	}
+	if policy.Version.Pattern != "" {
+		if _, err := regexp.Compile(policy.Version.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid version pattern in policy %s: %w", path, err)
+		}
+	}
+	return policy, nil
+}
+
+// checkPolicy evaluates the policy after the environment is validated. In
+// audit mode violations are logged instead of failing the registration.
+func checkPolicy(ctx context.Context, config *Config) (err error) {
+	_, span := startSpan(ctx, StepPolicy)
+	defer func() { endSpan(span, err) }()
+
+	logger := stepLogger(StepPolicy)
+	switch config.PolicyMode {
+	case "", PolicyModeEnforce, PolicyModeAudit:
+	default:
+		return fmt.Errorf("invalid policy mode %q, expected enforce or audit", config.PolicyMode)
+	}
+	policy := config.policy
+	if policy == nil {
+		policy, err = LoadPolicy(config.PolicyPath)
+		if err != nil {
+			return err
+		}
+	}
+	violations := policy.Evaluate(config)
+	span.SetAttributes(attribute.Int("policy.violations", len(violations)))
+	if len(violations) == 0 {
+		logger.Info("Artifact complies with policy", "policy", config.PolicyPath)
+		return nil
+	}
+	if config.PolicyMode == PolicyModeAudit {
+		for _, violation := range violations {
+			logger.Warn("Policy violation", "rule", violation.Rule, "message", violation.Message)
+		}
+		logger.Warn("Registering artifact despite policy violations in audit mode", "violations", len(violations))
+		return nil
+	}
+	return &PolicyError{Path: config.PolicyPath, Violations: violations}
+}
+
+// Evaluate returns the rules of the policy violated by the artifact.
+func (policy *Policy) Evaluate(config *Config) []PolicyViolation {
+	var violations []PolicyViolation
+	violate := func(rule, format string, args ...any) {
+		violations = append(violations, PolicyViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(policy.AllowedHosts) > 0 {
+		host := artifactHost(config.ArtifactUrl)
+		if host == "" && isPackageSpec(config) {
+			stepLogger(StepPolicy).Warn("Allowed hosts not checked, the package is resolved from the registry configured in the build",
+				"url", config.ArtifactUrl, "type", config.ArtifactType)
+		} else if host == "" {
+			violate(PolicyRuleAllowedHosts, "cannot determine the host of %s", config.ArtifactUrl)
+		} else if !matchesAny(policy.AllowedHosts, host) {
+			violate(PolicyRuleAllowedHosts, "host %s is not one of %s", host, strings.Join(policy.AllowedHosts, ", "))
+		}
+	}
+
+	if config.ArtifactDigest == "" && slices.ContainsFunc(policy.RequireDigest, func(artifactType string) bool {
+		return artifactType == "*" || strings.EqualFold(artifactType, config.ArtifactType)
+	}) {
+		violate(PolicyRuleRequireDigest, "a digest is required for artifacts of type %s", firstNonEmpty(config.ArtifactType, "*"))
+	}
+
+	version := policy.Version
+	switch version.Scheme {
+	case VersionSchemeSemver:
+		match := semverRegexp.FindStringSubmatch(config.ArtifactVersion)
+		if match == nil {
+			violate(PolicyRuleVersion, "version %s is not a SemVer version", config.ArtifactVersion)
+		} else if match[4] != "" && !version.AllowPrerelease {
+			violate(PolicyRuleVersion, "pre-release version %s is not allowed", config.ArtifactVersion)
+		}
+	case VersionSchemeCalver:
+		if !calverRegexp.MatchString(config.ArtifactVersion) {
+			violate(PolicyRuleVersion, "version %s is not a CalVer version such as 2024.06.1", config.ArtifactVersion)
+		}
+	}
+	if version.Pattern != "" && !regexp.MustCompile(version.Pattern).MatchString(config.ArtifactVersion) {
+		violate(PolicyRuleVersion, "version %s does not match %s", config.ArtifactVersion, version.Pattern)
+	}
+
+	labels := strings.Split(config.ArtifactLabel, ",")
+	for i := range labels {
+		labels[i] = strings.TrimSpace(labels[i])
+	}
+	for _, label := range policy.RequiredLabels {
+		if !slices.Contains(labels, label) {
+			violate(PolicyRuleRequiredLabels, "label %s is missing", label)
+		}
+	}
+
+	if len(policy.Branches) > 0 {
+		_, ref := splitWorkflowRef(config)
+		if !matchesAny(policy.Branches, ref) {
+			violate(PolicyRuleBranches, "ref %s is not one of %s", firstNonEmpty(ref, "<none>"), strings.Join(policy.Branches, ", "))
+		}
+	}
+	return violations
+}
+
+// artifactHost returns the host of an artifact URL, or the registry of an
+// image reference.
+func artifactHost(artifactUrl string) string {
+	if strings.Contains(artifactUrl, "://") && !strings.HasPrefix(artifactUrl, "docker://") && !strings.HasPrefix(artifactUrl, "oci://") {
+		parsed, err := url.Parse(artifactUrl)
+		if err != nil {
+			return ""
+		}
+		return strings.ToLower(parsed.Hostname())
+	}
+	reference, err := ParseDockerReference(artifactUrl)
+	if err != nil {
+		return ""
+	}
+	registry, _, _ := strings.Cut(reference.Url, "/")
+	if host, _, err := net.SplitHostPort(registry); err == nil {
+		return host
+	}
+	return registry
+}
+
+// isPackageSpec reports whether the artifact URL is a Maven, npm or PyPI
+// package spec such as group:artifact:version, which names no host.
+func isPackageSpec(config *Config) bool {
+	switch strings.ToLower(config.ArtifactType) {
+	case "maven", "npm", "pypi":
+		return !strings.Contains(config.ArtifactUrl, "://")
+	}
+	return false
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, value); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writePolicy(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	config := func() *Config {
+		return &Config{
+			ArtifactUrl:     "ghcr.io/owner/app:1.2.3",
+			ArtifactVersion: "1.2.3",
+			ArtifactType:    "docker",
+			ArtifactDigest:  testDigest,
+			ArtifactLabel:   "team-a, production",
+			GhaRepository:   "owner/repo",
+			GhaWorkflowRef:  "owner/repo/.github/workflows/build.yml@refs/heads/main",
+		}
+	}
+
+	for _, tc := range []struct {
+		name       string
+		policy     Policy
+		update     func(*Config)
+		violations []PolicyViolation
+	}{
+		{"Empty policy", Policy{}, func(*Config) {}, nil},
+		{"Allowed registry", Policy{AllowedHosts: []string{"ghcr.io"}}, func(*Config) {}, nil},
+		{"Allowed host wildcard", Policy{AllowedHosts: []string{"*.jfrog.io"}},
+			func(c *Config) { c.ArtifactUrl = "https://acme.jfrog.io/artifactory/libs/app-1.2.3.jar" }, nil},
+		{"Docker Hub", Policy{AllowedHosts: []string{"ghcr.io"}}, func(c *Config) { c.ArtifactUrl = "nginx:1.2.3" },
+			[]PolicyViolation{{PolicyRuleAllowedHosts, "host docker.io is not one of ghcr.io"}}},
+		{"Registry with port", Policy{AllowedHosts: []string{"localhost"}}, func(c *Config) { c.ArtifactUrl = "localhost:5000/app:1.2.3" }, nil},
+		{"Maven coordinates", Policy{AllowedHosts: []string{"ghcr.io"}}, func(c *Config) { c.ArtifactUrl, c.ArtifactType = "com.acme:app:1.2.3", "maven" }, nil},
+		{"npm spec", Policy{AllowedHosts: []string{"ghcr.io"}}, func(c *Config) { c.ArtifactUrl, c.ArtifactType = "@acme/app@1.2.3", "npm" }, nil},
+		{"Unknown host", Policy{AllowedHosts: []string{"ghcr.io"}}, func(c *Config) { c.ArtifactUrl, c.ArtifactType = "com.acme:app:1.2.3", "" },
+			[]PolicyViolation{{PolicyRuleAllowedHosts, "cannot determine the host of com.acme:app:1.2.3"}}},
+		{"Missing digest", Policy{RequireDigest: []string{"docker"}}, func(c *Config) { c.ArtifactDigest = "" },
+			[]PolicyViolation{{PolicyRuleRequireDigest, "a digest is required for artifacts of type docker"}}},
+		{"Missing digest of type in upper case", Policy{RequireDigest: []string{"Docker"}}, func(c *Config) { c.ArtifactDigest, c.ArtifactType = "", "DOCKER" },
+			[]PolicyViolation{{PolicyRuleRequireDigest, "a digest is required for artifacts of type DOCKER"}}},
+		{"Digest not required for type", Policy{RequireDigest: []string{"docker"}}, func(c *Config) { c.ArtifactDigest, c.ArtifactType = "", "maven" }, nil},
+		{"SemVer", Policy{Version: VersionPolicy{Scheme: VersionSchemeSemver}}, func(c *Config) { c.ArtifactVersion = "v1.2.3+build.5" }, nil},
+		{"Not SemVer", Policy{Version: VersionPolicy{Scheme: VersionSchemeSemver}}, func(c *Config) { c.ArtifactVersion = "1.2" },
+			[]PolicyViolation{{PolicyRuleVersion, "version 1.2 is not a SemVer version"}}},
+		{"Pre-release", Policy{Version: VersionPolicy{Scheme: VersionSchemeSemver}}, func(c *Config) { c.ArtifactVersion = "1.2.3-rc.1" },
+			[]PolicyViolation{{PolicyRuleVersion, "pre-release version 1.2.3-rc.1 is not allowed"}}},
+		{"Allowed pre-release", Policy{Version: VersionPolicy{Scheme: VersionSchemeSemver, AllowPrerelease: true}}, func(c *Config) { c.ArtifactVersion = "1.2.3-rc.1" }, nil},
+		{"CalVer", Policy{Version: VersionPolicy{Scheme: VersionSchemeCalver}}, func(c *Config) { c.ArtifactVersion = "2024.06.15.2" }, nil},
+		{"Not CalVer", Policy{Version: VersionPolicy{Scheme: VersionSchemeCalver}}, func(*Config) {},
+			[]PolicyViolation{{PolicyRuleVersion, "version 1.2.3 is not a CalVer version such as 2024.06.1"}}},
+		{"Version pattern", Policy{Version: VersionPolicy{Pattern: `^1\.`}}, func(c *Config) { c.ArtifactVersion = "2.0.0" },
+			[]PolicyViolation{{PolicyRuleVersion, `version 2.0.0 does not match ^1\.`}}},
+		{"Required labels", Policy{RequiredLabels: []string{"production", "team-b"}}, func(*Config) {},
+			[]PolicyViolation{{PolicyRuleRequiredLabels, "label team-b is missing"}}},
+		{"Release branch", Policy{Branches: []string{"refs/heads/main", "refs/heads/release/*"}},
+			func(c *Config) { c.GhaWorkflowRef = "owner/repo/.github/workflows/build.yml@refs/heads/release/1.2" }, nil},
+		{"Feature branch", Policy{Branches: []string{"refs/heads/main", "refs/tags/v*"}},
+			func(c *Config) { c.GhaWorkflowRef = "owner/repo/.github/workflows/build.yml@refs/heads/feature" },
+			[]PolicyViolation{{PolicyRuleBranches, "ref refs/heads/feature is not one of refs/heads/main, refs/tags/v*"}}},
+		{"Nested branch", Policy{Branches: []string{"refs/heads/*"}},
+			func(c *Config) { c.GhaWorkflowRef = "owner/repo/.github/workflows/build.yml@refs/heads/feature/x" },
+			[]PolicyViolation{{PolicyRuleBranches, "ref refs/heads/feature/x is not one of refs/heads/*"}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := config()
+			tc.update(c)
+			assert.Equal(t, tc.violations, tc.policy.Evaluate(c))
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	policy, err := LoadPolicy(writePolicy(t, `
+allowed-hosts: [ghcr.io, "*.jfrog.io"]
+require-digest: ["*"]
+version:
+  scheme: semver
+  allow-prerelease: true
+required-labels: [production]
+branches: [refs/heads/main]
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, &Policy{
+		AllowedHosts:   []string{"ghcr.io", "*.jfrog.io"},
+		RequireDigest:  []string{"*"},
+		Version:        VersionPolicy{Scheme: VersionSchemeSemver, AllowPrerelease: true},
+		RequiredLabels: []string{"production"},
+		Branches:       []string{"refs/heads/main"},
+	}, policy)
+
+	policy, err = LoadPolicy(writePolicy(t, `{"branches": ["refs/heads/main"]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"refs/heads/main"}, policy.Branches)
+
+	path := writePolicy(t, "allowed-registries: [ghcr.io]\n")
+	_, err = LoadPolicy(path)
+	assert.Contains(t, err.Error(), "failed to parse policy "+path)
+
+	path = writePolicy(t, "version:\n  scheme: romver\n")
+	_, err = LoadPolicy(path)
+	assert.Equal(t, `invalid version scheme "romver" in policy `+path+", expected semver or calver", err.Error())
+}
+
+func TestCheckPolicy(t *testing.T) {
+	path := writePolicy(t, "required-labels: [production]\nbranches: [refs/tags/*]\n")
+
+	t.Run("Enforce", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		_, err := (&Config{PolicyPath: path, PolicyMode: PolicyModeEnforce}).Run(context.Background())
+		var policyErr *PolicyError
+		assert.True(t, errors.As(err, &policyErr))
+		assert.Equal(t, "artifact violates 2 rule(s) of policy "+path+"\n"+
+			"  - required-labels: label production is missing\n"+
+			"  - branches: ref refs/heads/main is not one of refs/tags/*", err.Error())
+		assert.Equal(t, ExitPolicyViolation, ExitCode(err))
+		assert.Empty(t, server.Events())
+	})
+
+	t.Run("Audit", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		_, err := (&Config{PolicyPath: path, PolicyMode: PolicyModeAudit}).Run(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, server.Events(), 1)
+	})
+
+	t.Run("Invalid mode", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		_, err := (&Config{PolicyPath: path, PolicyMode: "warn"}).Run(context.Background())
+		assert.Equal(t, `invalid policy mode "warn", expected enforce or audit`, err.Error())
+	})
+}
@@ -0,0 +1,166 @@
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type ProvenanceStatement struct {
+	Type          string              `json:"_type"`
+	Subject       []ProvenanceSubject `json:"subject"`
+	PredicateType string              `json:"predicateType"`
+	Predicate     ProvenancePredicate `json:"predicate"`
+}
+
+type ProvenanceSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type ProvenancePredicate struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   map[string]any       `json:"externalParameters"`
+	InternalParameters   map[string]any       `json:"internalParameters,omitempty"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+type ResourceDescriptor struct {
+	Uri    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+type RunDetails struct {
+	Builder  Builder       `json:"builder"`
+	Metadata BuildMetadata `json:"metadata"`
+}
+
+type Builder struct {
+	Id string `json:"id"`
+}
+
+type BuildMetadata struct {
+	InvocationId string `json:"invocationId"`
+}
+
+type ProvenanceInfo struct {
+	PredicateType string               `json:"predicate_type"`
+	Digest        string               `json:"digest"`
+	Statement     *ProvenanceStatement `json:"statement,omitempty"`
+}
+
+// attachProvenance generates the SLSA provenance statement for the artifact,
+// optionally writes it to disk and adds it to the event data.
+func attachProvenance(config *Config, output *Output) error {
+	statement, err := generateProvenance(config)
+	if err != nil {
+		return err
+	}
+	statementJSON, err := json.Marshal(statement)
+	if err != nil {
+		return fmt.Errorf("failed to encode provenance statement: %w", err)
+	}
+	if config.ProvenancePath != "" {
+		if err := os.WriteFile(config.ProvenancePath, statementJSON, 0644); err != nil {
+			return fmt.Errorf("failed to write provenance statement: %w", err)
+		}
+		stepLogger(StepProvenance).Info("Provenance statement written", "path", config.ProvenancePath)
+	}
+	sum := sha256.Sum256(statementJSON)
+	output.Provenance = &ProvenanceInfo{
+		PredicateType: SlsaProvenancePredicateType,
+		Digest:        "sha256:" + hex.EncodeToString(sum[:]),
+		Statement:     statement,
+	}
+	return nil
+}
+
+func generateProvenance(config *Config) (*ProvenanceStatement, error) {
+	digest, err := parseDigest(config.ArtifactDigest)
+	if err != nil {
+		return nil, err
+	}
+
+	serverUrl := getServerUrl(config)
+	workflowPath, workflowRef := splitWorkflowRef(config)
+	repositoryUrl := serverUrl + "/" + config.GhaRepository
+
+	// The ref may move, the commit pins the source that was built.
+	source := ResourceDescriptor{Uri: "git+" + repositoryUrl + "@" + workflowRef}
+	if commit := os.Getenv(GithubSha); commit != "" {
+		source.Digest = map[string]string{"gitCommit": commit}
+	}
+
+	statement := &ProvenanceStatement{
+		Type: InTotoStatementType,
+		Subject: []ProvenanceSubject{{
+			Name:   config.ArtifactName,
+			Digest: digest,
+		}},
+		PredicateType: SlsaProvenancePredicateType,
+		Predicate: ProvenancePredicate{
+			BuildDefinition: BuildDefinition{
+				BuildType: GithubWorkflowBuildType,
+				ExternalParameters: map[string]any{
+					"workflow": map[string]string{
+						"ref":        workflowRef,
+						"repository": repositoryUrl,
+						"path":       workflowPath,
+					},
+				},
+				InternalParameters: map[string]any{
+					"github": map[string]string{
+						"run_id":      config.GhaRunId,
+						"run_attempt": config.GhaRunAttempt,
+						"run_number":  config.GhaRunNumber,
+						"job":         config.GhaJobName,
+					},
+				},
+				ResolvedDependencies: []ResourceDescriptor{source},
+			},
+			RunDetails: RunDetails{
+				Builder: Builder{Id: serverUrl + "/" + config.GhaWorkflowRef},
+				Metadata: BuildMetadata{
+					InvocationId: repositoryUrl + "/actions/runs/" + config.GhaRunId + "/attempts/" + config.GhaRunAttempt,
+				},
+			},
+		},
+	}
+	return statement, nil
+}
+
+func parseDigest(digest string) (map[string]string, error) {
+	if digest == "" {
+		return nil, fmt.Errorf(ArtifactDigest + " is required to generate provenance")
+	}
+	algorithm, value, found := strings.Cut(digest, ":")
+	if !found || algorithm == "" || value == "" {
+		return nil, fmt.Errorf("invalid artifact digest %q, expected <algorithm>:<hex>", digest)
+	}
+	if _, err := hex.DecodeString(value); err != nil {
+		return nil, fmt.Errorf("invalid artifact digest %q, expected <algorithm>:<hex>", digest)
+	}
+	return map[string]string{strings.ToLower(algorithm): strings.ToLower(value)}, nil
+}
+
+// splitWorkflowRef splits "owner/repo/.github/workflows/build.yml@refs/heads/main"
+// into the workflow path relative to the repository and the git ref.
+func splitWorkflowRef(config *Config) (string, string) {
+	workflow, ref, _ := strings.Cut(config.GhaWorkflowRef, "@")
+	return strings.TrimPrefix(workflow, config.GhaRepository+"/"), ref
+}
+
+func getServerUrl(config *Config) string {
+	if config.GhaServerUrl == "" {
+		return DefaultGithubServerUrl
+	}
+	return strings.TrimSuffix(config.GhaServerUrl, "/")
+}
@@ -0,0 +1,88 @@
+package artifacts
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func provenanceConfig() *Config {
+	return &Config{
+		ArtifactName:   "testartifact",
+		ArtifactDigest: "sha256:ABCDEF0123",
+		GhaRunId:       "123456789",
+		GhaRunAttempt:  "2",
+		GhaRunNumber:   "123",
+		GhaJobName:     "testjob",
+		GhaRepository:  "SrimanPadmanabanCB/gha-action",
+		GhaWorkflowRef: "SrimanPadmanabanCB/gha-action/.github/workflows/test_action.yml@refs/heads/main",
+	}
+}
+
+func TestGenerateProvenance(t *testing.T) {
+
+	t.Run("Statement", func(t *testing.T) {
+		t.Setenv(GithubSha, "a1b2c3d4")
+		statement, err := generateProvenance(provenanceConfig())
+		assert.Nil(t, err)
+		assert.Equal(t, InTotoStatementType, statement.Type)
+		assert.Equal(t, SlsaProvenancePredicateType, statement.PredicateType)
+		assert.Equal(t, "testartifact", statement.Subject[0].Name)
+		assert.Equal(t, map[string]string{"sha256": "abcdef0123"}, statement.Subject[0].Digest)
+		assert.Equal(t, map[string]string{
+			"ref":        "refs/heads/main",
+			"repository": "https://github.com/SrimanPadmanabanCB/gha-action",
+			"path":       ".github/workflows/test_action.yml",
+		}, statement.Predicate.BuildDefinition.ExternalParameters["workflow"])
+		assert.Equal(t, []ResourceDescriptor{{
+			Uri:    "git+https://github.com/SrimanPadmanabanCB/gha-action@refs/heads/main",
+			Digest: map[string]string{"gitCommit": "a1b2c3d4"},
+		}}, statement.Predicate.BuildDefinition.ResolvedDependencies)
+		assert.Equal(t, "https://github.com/SrimanPadmanabanCB/gha-action/.github/workflows/test_action.yml@refs/heads/main", statement.Predicate.RunDetails.Builder.Id)
+		assert.Equal(t, "https://github.com/SrimanPadmanabanCB/gha-action/actions/runs/123456789/attempts/2", statement.Predicate.RunDetails.Metadata.InvocationId)
+	})
+
+	t.Run("Server URL", func(t *testing.T) {
+		config := provenanceConfig()
+		config.GhaServerUrl = "https://ghes.example.com/"
+		statement, err := generateProvenance(config)
+		assert.Nil(t, err)
+		assert.Equal(t, "git+https://ghes.example.com/SrimanPadmanabanCB/gha-action@refs/heads/main", statement.Predicate.BuildDefinition.ResolvedDependencies[0].Uri)
+	})
+
+	t.Run("Missing digest", func(t *testing.T) {
+		config := provenanceConfig()
+		config.ArtifactDigest = ""
+		_, err := generateProvenance(config)
+		assert.NotNil(t, err)
+		assert.Equal(t, ArtifactDigest+" is required to generate provenance", err.Error())
+	})
+
+	t.Run("Invalid digest", func(t *testing.T) {
+		config := provenanceConfig()
+		config.ArtifactDigest = "test"
+		_, err := generateProvenance(config)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "invalid artifact digest")
+	})
+}
+
+func TestAttachProvenance(t *testing.T) {
+	config := provenanceConfig()
+	config.ProvenancePath = filepath.Join(t.TempDir(), "provenance.json")
+	output := prepareCloudEventData(config)
+
+	err := attachProvenance(config, &output)
+	assert.Nil(t, err)
+	assert.Equal(t, SlsaProvenancePredicateType, output.Provenance.PredicateType)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", output.Provenance.Digest)
+
+	data, err := os.ReadFile(config.ProvenancePath)
+	assert.Nil(t, err)
+	statement, err := json.Marshal(output.Provenance.Statement)
+	assert.Nil(t, err)
+	assert.Equal(t, statement, data)
+}
@@ -0,0 +1,100 @@
+package artifacts
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Backoff after a 429 response without a retry delay, overridden in tests.
+var (
+	rateLimitInitialBackoff = time.Second
+	rateLimitMaxBackoff     = 30 * time.Second
+)
+
+// rateLimiter is a token bucket shared by the registrations of a batch. Each
+// 429 response halves the rate and pauses every registration for the retry
+// delay; each accepted event recovers a tenth of the configured rate.
+type rateLimiter struct {
+	mu          sync.Mutex
+	limit       float64
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+func newRateLimiter(limit float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		limit:  limit,
+		rate:   limit,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	delay := l.reserve(time.Now())
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var delay time.Duration
+	if l.pausedUntil.After(now) {
+		delay = l.pausedUntil.Sub(now)
+	}
+	if l.rate <= 0 {
+		return delay
+	}
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	if l.tokens < 0 {
+		delay = max(delay, time.Duration(-l.tokens/l.rate*float64(time.Second)))
+	}
+	return delay
+}
+
+// slowDown halves the rate down to a sixteenth of the limit and pauses the
+// batch for the retry delay. It returns how long the batch is paused.
+func (l *rateLimiter) slowDown(retryDelay time.Duration) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.limit > 0 {
+		l.rate = max(l.rate/2, l.limit/16)
+	}
+	if pausedUntil := now.Add(retryDelay); pausedUntil.After(l.pausedUntil) {
+		l.pausedUntil = pausedUntil
+	}
+	l.tokens = min(l.tokens, 0)
+	return l.pausedUntil.Sub(now)
+}
+
+func (l *rateLimiter) speedUp() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit > 0 {
+		l.rate = min(l.limit, l.rate+l.limit/10)
+	}
+}
+
+func (l *rateLimiter) currentRate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
@@ -0,0 +1,39 @@
+package artifacts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(2, 1)
+	now := limiter.last
+	assert.Zero(t, limiter.reserve(now))
+	assert.Equal(t, 500*time.Millisecond, limiter.reserve(now))
+	assert.Equal(t, time.Second, limiter.reserve(now))
+	// The bucket refills at the rate up to the burst.
+	assert.Equal(t, 500*time.Millisecond, limiter.reserve(now.Add(time.Second)))
+
+	paused := limiter.slowDown(time.Minute)
+	assert.InDelta(t, time.Minute, paused, float64(time.Second))
+	assert.Equal(t, 1.0, limiter.currentRate())
+	for range 10 {
+		limiter.slowDown(0)
+	}
+	assert.Equal(t, 2.0/16, limiter.currentRate())
+
+	for range 20 {
+		limiter.speedUp()
+	}
+	assert.Equal(t, 2.0, limiter.currentRate())
+
+	unlimited := newRateLimiter(0, 4)
+	for range 10 {
+		assert.Zero(t, unlimited.reserve(time.Now()))
+	}
+	unlimited.slowDown(time.Minute)
+	assert.Greater(t, unlimited.reserve(time.Now()), 59*time.Second)
+	assert.Zero(t, unlimited.currentRate())
+}
@@ -0,0 +1,322 @@
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ArtifactReference is the parsed form of an ArtifactUrl for a known artifact type.
+type ArtifactReference struct {
+	Url     string
+	Name    string
+	Version string
+	Digest  string
+}
+
+type referenceParser func(string) (*ArtifactReference, error)
+
+var referenceParsers = map[string]referenceParser{
+	"docker":    ParseDockerReference,
+	"oci":       ParseDockerReference,
+	"container": ParseDockerReference,
+	"maven":     parseMavenReference,
+	"npm":       parseNpmReference,
+	"pypi":      parsePypiReference,
+	"helm":      parseHelmReference,
+	"generic":   parseGenericUrl,
+}
+
+var (
+	dockerPathRegexp    = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	dockerTagRegexp     = regexp.MustCompile(`^\w[\w.-]{0,127}$`)
+	digestRegexp        = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[0-9a-fA-F]{32,}$`)
+	mavenPartRegexp     = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	npmNameRegexp       = regexp.MustCompile(`^(?:@[a-z0-9-~][a-z0-9-._~]*/)?[a-z0-9-~][a-z0-9-._~]*$`)
+	pypiNameRegexp      = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9._-]*[A-Za-z0-9])?$`)
+	pypiSeparatorRegexp = regexp.MustCompile(`[-_.]+`)
+	helmArchiveRegexp   = regexp.MustCompile(`^(.+?)-(v?\d.*)\.tgz$`)
+)
+
+// validateArtifactUrl parses the artifact URL according to the artifact type,
+// replaces it with its normalized form and cross-checks the declared version
+// and digest against the reference. Unknown artifact types are not validated.
+func validateArtifactUrl(config *Config) error {
+	artifactType := strings.ToLower(config.ArtifactType)
+	parse, ok := referenceParsers[artifactType]
+	if !ok {
+		return nil
+	}
+	reference, err := parse(config.ArtifactUrl)
+	if err != nil {
+		return fmt.Errorf("invalid %s artifact url %q: %w", artifactType, config.ArtifactUrl, err)
+	}
+	if reference.Version != "" && !versionMatches(reference.Version, config.ArtifactVersion) {
+		return fmt.Errorf("artifact version %q does not match version %q in artifact url %q", config.ArtifactVersion, reference.Version, config.ArtifactUrl)
+	}
+	if reference.Digest != "" && config.ArtifactDigest != "" && !strings.EqualFold(reference.Digest, config.ArtifactDigest) {
+		return fmt.Errorf("artifact digest %q does not match digest %q in artifact url %q", config.ArtifactDigest, reference.Digest, config.ArtifactUrl)
+	}
+	config.ArtifactUrl = reference.Url
+	if config.ArtifactDigest == "" {
+		config.ArtifactDigest = reference.Digest
+	}
+	return nil
+}
+
+func versionMatches(referenceVersion string, version string) bool {
+	return strings.TrimPrefix(referenceVersion, "v") == strings.TrimPrefix(version, "v")
+}
+
+// ParseDockerReference parses a docker/OCI image reference of the form
+// [registry/]repository[:tag][@digest], defaulting to Docker Hub.
+func ParseDockerReference(value string) (*ArtifactReference, error) {
+	reference := strings.TrimPrefix(strings.TrimPrefix(value, "docker://"), "oci://")
+	if reference == "" {
+		return nil, errors.New("empty image reference")
+	}
+
+	var digest string
+	if name, value, found := strings.Cut(reference, "@"); found {
+		if !digestRegexp.MatchString(value) {
+			return nil, fmt.Errorf("invalid digest %q", value)
+		}
+		reference, digest = name, value
+	}
+
+	var tag string
+	if i := strings.LastIndex(reference, ":"); i > strings.LastIndex(reference, "/") {
+		reference, tag = reference[:i], reference[i+1:]
+		if !dockerTagRegexp.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q", tag)
+		}
+	}
+
+	registry, repository := DockerHubRegistry, reference
+	if first, rest, found := strings.Cut(reference, "/"); found &&
+		(strings.ContainsAny(first, ".:") || first == "localhost") {
+		registry, repository = strings.ToLower(first), rest
+	}
+	if registry == DockerHubRegistry && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+	if !dockerPathRegexp.MatchString(repository) {
+		return nil, fmt.Errorf("invalid repository %q", repository)
+	}
+
+	normalized := registry + "/" + repository
+	if tag != "" {
+		normalized += ":" + tag
+	}
+	if digest != "" {
+		normalized += "@" + digest
+	}
+	return &ArtifactReference{
+		Url:     normalized,
+		Name:    repository,
+		Version: tag,
+		Digest:  digest,
+	}, nil
+}
+
+// parseMavenReference accepts GAV coordinates
+// (groupId:artifactId[:extension[:classifier]]:version) or the URL of a file
+// in a Maven repository layout (.../group/path/artifactId/version/artifactId-version.ext).
+func parseMavenReference(value string) (*ArtifactReference, error) {
+	if !strings.Contains(value, "://") {
+		parts := strings.Split(value, ":")
+		if len(parts) < 3 || len(parts) > 5 {
+			return nil, errors.New("expected groupId:artifactId[:extension[:classifier]]:version")
+		}
+		for _, part := range parts {
+			if !mavenPartRegexp.MatchString(part) {
+				return nil, fmt.Errorf("invalid coordinate %q", part)
+			}
+		}
+		return &ArtifactReference{
+			Url:     value,
+			Name:    parts[0] + ":" + parts[1],
+			Version: parts[len(parts)-1],
+		}, nil
+	}
+
+	artifactUrl, err := parseHttpsUrl(value)
+	if err != nil {
+		return nil, err
+	}
+	segments := strings.Split(strings.Trim(artifactUrl.Path, "/"), "/")
+	if len(segments) < 4 {
+		return nil, errors.New("expected a maven repository layout path")
+	}
+	file := segments[len(segments)-1]
+	version := segments[len(segments)-2]
+	artifactId := segments[len(segments)-3]
+	if !strings.HasPrefix(file, artifactId+"-"+version) && !isMavenSnapshotFile(file, artifactId, version) {
+		return nil, fmt.Errorf("file %q does not match artifact %q version %q", file, artifactId, version)
+	}
+	return &ArtifactReference{
+		Url:     artifactUrl.String(),
+		Name:    artifactId,
+		Version: version,
+	}, nil
+}
+
+// isMavenSnapshotFile reports whether the file is a deployed snapshot of the
+// version, named <artifactId>-<base>-<yyyyMMdd.HHmmss>-<build>, e.g.
+// app-1.0-20230101.123456-1.jar in the 1.0-SNAPSHOT directory.
+func isMavenSnapshotFile(file, artifactId, version string) bool {
+	base, found := strings.CutSuffix(version, "-SNAPSHOT")
+	if !found {
+		return false
+	}
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(artifactId+"-"+base) + `-\d{8}\.\d{6}-\d+(?:[-.]|$)`).MatchString(file)
+}
+
+// parseNpmReference accepts name@version specs and npm registry tarball or
+// package page URLs.
+func parseNpmReference(value string) (*ArtifactReference, error) {
+	if !strings.Contains(value, "://") {
+		i := strings.LastIndex(value, "@")
+		if i <= 0 {
+			return nil, errors.New("expected [@scope/]name@version")
+		}
+		name, version := value[:i], value[i+1:]
+		if !npmNameRegexp.MatchString(name) || len(name) > 214 {
+			return nil, fmt.Errorf("invalid package name %q", name)
+		}
+		if version == "" {
+			return nil, errors.New("missing version")
+		}
+		return &ArtifactReference{Url: value, Name: name, Version: version}, nil
+	}
+
+	artifactUrl, err := parseHttpsUrl(value)
+	if err != nil {
+		return nil, err
+	}
+	urlPath := strings.Trim(artifactUrl.Path, "/")
+	var name, version string
+	if before, file, found := strings.Cut(urlPath, "/-/"); found {
+		// https://registry.npmjs.org/@scope/name/-/name-1.0.0.tgz
+		name = before
+		version = strings.TrimSuffix(strings.TrimPrefix(file, path.Base(name)+"-"), ".tgz")
+		if version == file || !strings.HasSuffix(file, ".tgz") {
+			return nil, fmt.Errorf("tarball %q does not match package %q", file, name)
+		}
+	} else if pkg, found := strings.CutPrefix(urlPath, "package/"); found {
+		// https://www.npmjs.com/package/@scope/name/v/1.0.0
+		name, version, _ = strings.Cut(pkg, "/v/")
+	} else {
+		return nil, errors.New("expected an npm registry tarball or package url")
+	}
+	if !npmNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid package name %q", name)
+	}
+	return &ArtifactReference{Url: artifactUrl.String(), Name: name, Version: version}, nil
+}
+
+// parsePypiReference accepts name==version specs, pypi.org project URLs and
+// sdist/wheel file URLs.
+func parsePypiReference(value string) (*ArtifactReference, error) {
+	if !strings.Contains(value, "://") {
+		name, version, found := strings.Cut(value, "==")
+		if !found || version == "" {
+			return nil, errors.New("expected name==version")
+		}
+		if !pypiNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("invalid project name %q", name)
+		}
+		name = normalizePypiName(name)
+		return &ArtifactReference{Url: name + "==" + version, Name: name, Version: version}, nil
+	}
+
+	artifactUrl, err := parseHttpsUrl(value)
+	if err != nil {
+		return nil, err
+	}
+	urlPath := strings.Trim(artifactUrl.Path, "/")
+	var name, version string
+	if project, found := strings.CutPrefix(urlPath, "project/"); found {
+		// https://pypi.org/project/name/1.0.0/
+		name, version, _ = strings.Cut(project, "/")
+	} else {
+		file := path.Base(urlPath)
+		switch {
+		case strings.HasSuffix(file, ".whl"):
+			parts := strings.Split(strings.TrimSuffix(file, ".whl"), "-")
+			if len(parts) < 5 {
+				return nil, fmt.Errorf("invalid wheel file name %q", file)
+			}
+			name, version = parts[0], parts[1]
+		case strings.HasSuffix(file, ".tar.gz"), strings.HasSuffix(file, ".zip"):
+			base := strings.TrimSuffix(strings.TrimSuffix(file, ".tar.gz"), ".zip")
+			i := strings.LastIndex(base, "-")
+			if i <= 0 {
+				return nil, fmt.Errorf("invalid sdist file name %q", file)
+			}
+			name, version = base[:i], base[i+1:]
+		default:
+			return nil, errors.New("expected a pypi project url or a wheel/sdist file url")
+		}
+	}
+	if !pypiNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid project name %q", name)
+	}
+	return &ArtifactReference{Url: artifactUrl.String(), Name: normalizePypiName(name), Version: version}, nil
+}
+
+// normalizePypiName normalizes a project name as described in PEP 503.
+func normalizePypiName(name string) string {
+	return strings.ToLower(pypiSeparatorRegexp.ReplaceAllString(name, "-"))
+}
+
+// parseHelmReference accepts OCI chart references (oci://registry/chart:version)
+// and chart archive URLs (https://.../chart-1.0.0.tgz).
+func parseHelmReference(value string) (*ArtifactReference, error) {
+	if strings.HasPrefix(value, "oci://") {
+		reference, err := ParseDockerReference(value)
+		if err != nil {
+			return nil, err
+		}
+		reference.Url = "oci://" + reference.Url
+		reference.Name = path.Base(reference.Name)
+		return reference, nil
+	}
+
+	artifactUrl, err := parseHttpsUrl(value)
+	if err != nil {
+		return nil, err
+	}
+	matches := helmArchiveRegexp.FindStringSubmatch(path.Base(artifactUrl.Path))
+	if matches == nil {
+		return nil, errors.New("expected an oci:// chart reference or a <chart>-<version>.tgz url")
+	}
+	return &ArtifactReference{Url: artifactUrl.String(), Name: matches[1], Version: matches[2]}, nil
+}
+
+func parseGenericUrl(value string) (*ArtifactReference, error) {
+	artifactUrl, err := parseHttpsUrl(value)
+	if err != nil {
+		return nil, err
+	}
+	return &ArtifactReference{Url: artifactUrl.String()}, nil
+}
+
+func parseHttpsUrl(value string) (*url.URL, error) {
+	artifactUrl, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(artifactUrl.Scheme, "https") {
+		return nil, errors.New("expected an https url")
+	}
+	if artifactUrl.Host == "" {
+		return nil, errors.New("missing host")
+	}
+	artifactUrl.Scheme = "https"
+	artifactUrl.Host = strings.ToLower(artifactUrl.Host)
+	return artifactUrl, nil
+}
@@ -0,0 +1,77 @@
+package artifacts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestValidateArtifactUrl(t *testing.T) {
+	tests := []struct {
+		name         string
+		artifactType string
+		url          string
+		version      string
+		wantUrl      string
+		wantErr      string
+	}{
+		{"docker hub short name", "docker", "nginx:1.25", "1.25", "docker.io/library/nginx:1.25", ""},
+		{"docker registry with port", "docker", "localhost:5000/team/app:v1.0.0@" + testDigest, "1.0.0", "localhost:5000/team/app:v1.0.0@" + testDigest, ""},
+		{"docker scheme", "Docker", "docker://GHCR.io/owner/app:2.0", "2.0", "ghcr.io/owner/app:2.0", ""},
+		{"docker digest only", "oci", "ghcr.io/owner/app@" + testDigest, "1.0.0", "ghcr.io/owner/app@" + testDigest, ""},
+		{"docker version mismatch", "docker", "ghcr.io/owner/app:latest", "1.0.1", "", `artifact version "1.0.1" does not match version "latest"`},
+		{"docker uppercase repository", "docker", "ghcr.io/Owner/App:1.0", "1.0", "", "invalid repository"},
+		{"docker invalid digest", "docker", "ghcr.io/owner/app@sha256:123", "1.0", "", "invalid digest"},
+		{"maven gav", "maven", "com.example:app:1.2.3", "1.2.3", "com.example:app:1.2.3", ""},
+		{"maven gav with classifier", "maven", "com.example:app:jar:sources:1.2.3", "1.2.3", "com.example:app:jar:sources:1.2.3", ""},
+		{"maven gav mismatch", "maven", "com.example:app:1.2.3", "1.2.4", "", "does not match version"},
+		{"maven url", "maven", "https://repo1.maven.org/maven2/com/example/app/1.2.3/app-1.2.3.jar", "1.2.3", "https://repo1.maven.org/maven2/com/example/app/1.2.3/app-1.2.3.jar", ""},
+		{"maven snapshot url", "maven", "https://repo.example.com/snapshots/com/example/app/1.0-SNAPSHOT/app-1.0-20230101.123456-1.jar", "1.0-SNAPSHOT", "https://repo.example.com/snapshots/com/example/app/1.0-SNAPSHOT/app-1.0-20230101.123456-1.jar", ""},
+		{"maven snapshot url of other version", "maven", "https://repo.example.com/snapshots/com/example/app/1.0-SNAPSHOT/app-1.1-20230101.123456-1.jar", "1.0-SNAPSHOT", "", "does not match artifact"},
+		{"maven url wrong file", "maven", "https://repo1.maven.org/maven2/com/example/app/1.2.3/other-1.2.3.jar", "1.2.3", "", "does not match artifact"},
+		{"maven invalid gav", "maven", "com.example:app", "1.2.3", "", "expected groupId:artifactId"},
+		{"npm spec", "npm", "@scope/pkg@1.0.0", "1.0.0", "@scope/pkg@1.0.0", ""},
+		{"npm tarball", "npm", "https://registry.npmjs.org/@scope/pkg/-/pkg-1.0.0.tgz", "1.0.0", "https://registry.npmjs.org/@scope/pkg/-/pkg-1.0.0.tgz", ""},
+		{"npm package page", "npm", "https://www.npmjs.com/package/pkg/v/2.0.0", "1.0.0", "", "does not match version"},
+		{"npm invalid name", "npm", "Pkg@1.0.0", "1.0.0", "", "invalid package name"},
+		{"pypi spec", "pypi", "My_Package==1.0.0", "1.0.0", "my-package==1.0.0", ""},
+		{"pypi project url", "pypi", "https://pypi.org/project/requests/2.31.0/", "2.31.0", "https://pypi.org/project/requests/2.31.0/", ""},
+		{"pypi wheel", "pypi", "https://files.pythonhosted.org/packages/ab/cd/requests-2.31.0-py3-none-any.whl", "2.31.0", "https://files.pythonhosted.org/packages/ab/cd/requests-2.31.0-py3-none-any.whl", ""},
+		{"pypi sdist mismatch", "pypi", "https://files.pythonhosted.org/packages/ab/cd/requests-2.31.0.tar.gz", "2.30.0", "", "does not match version"},
+		{"helm oci", "helm", "oci://ghcr.io/owner/charts/app:0.1.0", "0.1.0", "oci://ghcr.io/owner/charts/app:0.1.0", ""},
+		{"helm archive", "helm", "https://charts.example.com/my-app-0.1.0-rc.1.tgz", "0.1.0-rc.1", "https://charts.example.com/my-app-0.1.0-rc.1.tgz", ""},
+		{"helm invalid archive", "helm", "https://charts.example.com/index.yaml", "0.1.0", "", "expected an oci:// chart reference"},
+		{"generic https", "generic", "HTTPS://Example.com/file.zip", "1.0.0", "https://example.com/file.zip", ""},
+		{"generic http", "generic", "http://example.com/file.zip", "1.0.0", "", "expected an https url"},
+		{"unknown type", "custom", "anything", "1.0.0", "anything", ""},
+		{"no type", "", "https://test.com", "1.0.0", "https://test.com", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Config{ArtifactType: test.artifactType, ArtifactUrl: test.url, ArtifactVersion: test.version}
+			err := validateArtifactUrl(&config)
+			if test.wantErr != "" {
+				assert.NotNil(t, err)
+				assert.Contains(t, err.Error(), test.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.wantUrl, config.ArtifactUrl)
+		})
+	}
+
+	t.Run("Digest from reference", func(t *testing.T) {
+		config := Config{ArtifactType: "docker", ArtifactUrl: "ghcr.io/owner/app:1.0@" + testDigest, ArtifactVersion: "1.0"}
+		assert.Nil(t, validateArtifactUrl(&config))
+		assert.Equal(t, testDigest, config.ArtifactDigest)
+	})
+
+	t.Run("Digest mismatch", func(t *testing.T) {
+		config := Config{ArtifactType: "docker", ArtifactUrl: "ghcr.io/owner/app:1.0@" + testDigest, ArtifactVersion: "1.0", ArtifactDigest: "sha256:abc"}
+		err := validateArtifactUrl(&config)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "does not match digest")
+	})
+}
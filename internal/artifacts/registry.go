@@ -0,0 +1,191 @@
+package artifacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ImageIndex is an OCI image index or Docker manifest list.
+type ImageIndex struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []IndexManifest `json:"manifests"`
+	// Digest is the digest of the index itself.
+	Digest string `json:"-"`
+}
+
+// IndexManifest is an entry of an image index.
+type IndexManifest struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Platform    *ManifestPlatform `json:"platform,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ManifestPlatform is the platform an image manifest runs on.
+type ManifestPlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+func (platform *ManifestPlatform) String() string {
+	value := platform.OS + "/" + platform.Architecture
+	if platform.Variant != "" {
+		value += "/" + platform.Variant
+	}
+	return value
+}
+
+// IsIndex reports whether the manifest is an index rather than a single image.
+func (index *ImageIndex) IsIndex() bool {
+	return index.MediaType == MediaTypeOciIndex || index.MediaType == MediaTypeDockerManifestList
+}
+
+// Platforms returns the platform images of the index, leaving out entries
+// without a platform such as attestation manifests.
+func (index *ImageIndex) Platforms() []IndexManifest {
+	var manifests []IndexManifest
+	for _, manifest := range index.Manifests {
+		if manifest.Platform == nil || manifest.Platform.OS == "unknown" || manifest.Platform.Architecture == "unknown" {
+			continue
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests
+}
+
+// fetchImageIndex fetches the manifest of an image reference from its
+// registry, authenticating with the registry's bearer token service when
+// challenged.
+func fetchImageIndex(ctx context.Context, config *Config, reference *ArtifactReference) (*ImageIndex, error) {
+	registry, _, _ := strings.Cut(reference.Url, "/")
+	manifestRef := firstNonEmpty(reference.Digest, reference.Version, "latest")
+	manifestUrl := fmt.Sprintf("%s/v2/%s/manifests/%s", registryBaseUrl(registry), reference.Name, manifestRef)
+
+	resp, body, err := registryGet(ctx, config, manifestUrl, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err := registryToken(ctx, config, resp.Header.Get(WwwAuthenticateHeaderKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to authenticate with registry %s: %w", registry, err)
+		}
+		resp, body, err = registryGet(ctx, config, manifestUrl, token)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching manifest of %s - %s : %s", reference.Url, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	index := &ImageIndex{}
+	if err := json.Unmarshal(body, index); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest of %s: %w", reference.Url, err)
+	}
+	if index.MediaType == "" {
+		index.MediaType, _, _ = strings.Cut(resp.Header.Get(ContentTypeHeaderKey), ";")
+	}
+	index.Digest = resp.Header.Get(DockerContentDigestHeaderKey)
+	if index.Digest == "" {
+		sum := sha256.Sum256(body)
+		index.Digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return index, nil
+}
+
+func registryGet(ctx context.Context, config *Config, requestUrl string, token string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set(AcceptHeaderKey, strings.Join([]string{MediaTypeOciIndex, MediaTypeDockerManifestList, MediaTypeOciManifest, MediaTypeDockerManifest}, ", "))
+	if token != "" {
+		req.Header.Set(AuthorizationHeaderKey, Bearer+token)
+	} else if config.RegistryUsername != "" {
+		req.SetBasicAuth(config.RegistryUsername, config.RegistryPassword)
+	}
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching image manifest: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	return resp, body, nil
+}
+
+// registryToken requests a token from the realm of a Bearer challenge such as
+// Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:owner/app:pull".
+func registryToken(ctx context.Context, config *Config, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+	values := url.Values{}
+	var realm string
+	for _, param := range strings.Split(params, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		value = strings.Trim(value, `"`)
+		if key == "realm" {
+			realm = value
+		} else if key == "service" || key == "scope" {
+			values.Set(key, value)
+		}
+	}
+	if realm == "" {
+		return "", fmt.Errorf("authentication challenge %q has no realm", challenge)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+values.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if config.RegistryUsername != "" {
+		req.SetBasicAuth(config.RegistryUsername, config.RegistryPassword)
+	}
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with %s", resp.Status)
+	}
+	var tokenResp struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("failed to parse token response: %w", err)
+	}
+	return firstNonEmpty(tokenResp.Token, tokenResp.AccessToken), nil
+}
+
+// registryBaseUrl returns the API URL of a registry. Docker Hub serves its
+// API from a different host and loopback registries use plain HTTP.
+func registryBaseUrl(registry string) string {
+	if registry == DockerHubRegistry {
+		return "https://" + DockerHubApiHost
+	}
+	host := registry
+	if h, _, err := net.SplitHostPort(registry); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return "http://" + registry
+	}
+	return "https://" + registry
+}
@@ -0,0 +1,276 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gopkg.in/yaml.v3"
+)
+
+// Relationships links the artifact to other artifacts. All relationships point
+// from the registered artifact to the referenced one.
+type Relationships struct {
+	// Contains are the artifacts bundled in this one, e.g. the images of a
+	// Helm chart or the jars of a distribution.
+	Contains []ArtifactRef `yaml:"contains" json:"contains,omitempty"`
+	// BuiltFrom are the artifacts this one was built from, e.g. a base image.
+	BuiltFrom []ArtifactRef `yaml:"built-from" json:"built_from,omitempty"`
+	// DependsOn are the artifacts required at runtime.
+	DependsOn []ArtifactRef `yaml:"depends-on" json:"depends_on,omitempty"`
+}
+
+// ArtifactRef references an artifact by name and version, by digest or both.
+type ArtifactRef struct {
+	Name    string `yaml:"name" json:"name,omitempty"`
+	Version string `yaml:"version" json:"version,omitempty"`
+	Digest  string `yaml:"digest" json:"digest,omitempty"`
+}
+
+func (ref ArtifactRef) String() string {
+	switch {
+	case ref.Name == "":
+		return ref.Digest
+	case ref.Version == "":
+		return ref.Name + "@" + ref.Digest
+	case ref.Digest == "":
+		return ref.Name + "@" + ref.Version
+	}
+	return ref.Name + "@" + ref.Version + " (" + ref.Digest + ")"
+}
+
+// matches reports whether the reference designates the artifact. Digests are
+// compared when both sides have one, otherwise name and version.
+func (ref ArtifactRef) matches(name, version, digest string) bool {
+	if ref.Digest != "" && digest != "" {
+		return strings.EqualFold(ref.Digest, digest)
+	}
+	return ref.Name != "" && ref.Version != "" && ref.Name == name && ref.Version == version
+}
+
+func (ref ArtifactRef) validate() error {
+	if ref.Name == "" && ref.Digest == "" {
+		return fmt.Errorf("artifact reference requires a name or a digest")
+	}
+	if ref.Name != "" && ref.Version == "" && ref.Digest == "" {
+		return fmt.Errorf("artifact reference %q requires a version or a digest", ref.Name)
+	}
+	if ref.Digest != "" && !digestRegexp.MatchString(ref.Digest) {
+		return fmt.Errorf("invalid digest %q in artifact reference, expected algorithm:hex", ref.Digest)
+	}
+	return nil
+}
+
+// ParseArtifactRef parses name@version, name@digest or a bare digest such as
+// sha256:2c26b4... The name may start with @, e.g. @scope/package@1.0.0.
+func ParseArtifactRef(value string) (ArtifactRef, error) {
+	value = strings.TrimSpace(value)
+	if digestRegexp.MatchString(value) {
+		return ArtifactRef{Digest: value}, nil
+	}
+	i := strings.LastIndex(value, "@")
+	if i <= 0 || i == len(value)-1 {
+		return ArtifactRef{}, fmt.Errorf("invalid artifact reference %q, expected name@version, name@digest or a digest", value)
+	}
+	name, rest := value[:i], value[i+1:]
+	if digestRegexp.MatchString(rest) {
+		return ArtifactRef{Name: name, Digest: rest}, nil
+	}
+	return ArtifactRef{Name: name, Version: rest}, nil
+}
+
+// ParseRelationships parses the artifact references of each relationship, one
+// per line or value.
+func ParseRelationships(contains, builtFrom, dependsOn []string) (*Relationships, error) {
+	relationships := &Relationships{}
+	for _, relationship := range []struct {
+		values []string
+		refs   *[]ArtifactRef
+	}{
+		{contains, &relationships.Contains},
+		{builtFrom, &relationships.BuiltFrom},
+		{dependsOn, &relationships.DependsOn},
+	} {
+		for _, value := range relationship.values {
+			for _, line := range strings.Split(value, "\n") {
+				if strings.TrimSpace(line) == "" {
+					continue
+				}
+				ref, err := ParseArtifactRef(line)
+				if err != nil {
+					return nil, err
+				}
+				*relationship.refs = append(*relationship.refs, ref)
+			}
+		}
+	}
+	if relationships.IsEmpty() {
+		return nil, nil
+	}
+	return relationships, nil
+}
+
+// LoadRelationships reads a YAML or JSON relationships manifest.
+func LoadRelationships(path string) (*Relationships, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relationships: %w", err)
+	}
+	relationships := &Relationships{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(relationships); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse relationships %s: %w", path, err)
+	}
+	return relationships, nil
+}
+
+// IsEmpty reports whether there is no relationship.
+func (r *Relationships) IsEmpty() bool {
+	return r == nil || len(r.Contains)+len(r.BuiltFrom)+len(r.DependsOn) == 0
+}
+
+func (r *Relationships) merge(other *Relationships) {
+	if other == nil {
+		return
+	}
+	r.Contains = append(r.Contains, other.Contains...)
+	r.BuiltFrom = append(r.BuiltFrom, other.BuiltFrom...)
+	r.DependsOn = append(r.DependsOn, other.DependsOn...)
+}
+
+func (r *Relationships) each(fn func(kind string, ref ArtifactRef) error) error {
+	if r == nil {
+		return nil
+	}
+	for _, group := range []struct {
+		kind string
+		refs []ArtifactRef
+	}{
+		{RelationshipContains, r.Contains},
+		{RelationshipBuiltFrom, r.BuiltFrom},
+		{RelationshipDependsOn, r.DependsOn},
+	} {
+		for _, ref := range group.refs {
+			if err := fn(group.kind, ref); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkRelationships merges the relationships manifest into the flags and
+// rejects invalid references, self-references and relationships that close a
+// cycle with the artifacts registered earlier in the same job.
+func checkRelationships(ctx context.Context, config *Config) (err error) {
+	_, span := startSpan(ctx, StepRelationships)
+	defer func() { endSpan(span, err) }()
+
+	relationships := &Relationships{}
+	relationships.merge(config.Relationships)
+	if config.RelationshipsPath != "" {
+		manifest, err := LoadRelationships(config.RelationshipsPath)
+		if err != nil {
+			return err
+		}
+		relationships.merge(manifest)
+	}
+	if relationships.IsEmpty() {
+		config.Relationships = nil
+		return nil
+	}
+	config.Relationships = relationships
+
+	seen := map[string]bool{}
+	err = config.Relationships.each(func(kind string, ref ArtifactRef) error {
+		if err := ref.validate(); err != nil {
+			return fmt.Errorf("invalid %s relationship: %w", kind, err)
+		}
+		if ref.matches(config.ArtifactName, config.ArtifactVersion, config.ArtifactDigest) {
+			return fmt.Errorf("artifact %s@%s cannot reference itself in %s", config.ArtifactName, config.ArtifactVersion, kind)
+		}
+		key := kind + " " + ref.String()
+		if seen[key] {
+			return fmt.Errorf("artifact %s is listed twice in %s", ref, kind)
+		}
+		seen[key] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cycle, err := findRelationshipCycle(config)
+	if err != nil {
+		return err
+	}
+	if cycle != nil {
+		return fmt.Errorf("relationships of %s@%s form a cycle: %s", config.ArtifactName, config.ArtifactVersion, strings.Join(cycle, " -> "))
+	}
+	span.SetAttributes(attribute.Int("relationships.count", len(seen)))
+	stepLogger(StepRelationships).Debug("Relationships validated", "contains", len(config.Relationships.Contains), "built_from", len(config.Relationships.BuiltFrom), "depends_on", len(config.Relationships.DependsOn))
+	return nil
+}
+
+// findRelationshipCycle walks the relationships recorded in the state file
+// from the artifact and returns the path back to it, if any. The recorded
+// graph was acyclic, so a new cycle always passes through the artifact.
+func findRelationshipCycle(config *Config) ([]string, error) {
+	path := getStatePath(config)
+	if path == "" {
+		return nil, nil
+	}
+	state, err := readState(path)
+	if err != nil {
+		return nil, err
+	}
+	self := idempotencyKey(config)
+	nodes := map[string]StateEntry{self: {
+		ArtifactName:    config.ArtifactName,
+		ArtifactVersion: config.ArtifactVersion,
+		ArtifactDigest:  config.ArtifactDigest,
+		Relationships:   config.Relationships,
+	}}
+	for key, entry := range state.Registrations {
+		if key != self {
+			nodes[key] = entry
+		}
+	}
+
+	visited := map[string]bool{}
+	var walk func(key string, path []string) []string
+	walk = func(key string, path []string) []string {
+		entry := nodes[key]
+		path = append(path, entry.ArtifactName+"@"+entry.ArtifactVersion)
+		var cycle []string
+		_ = entry.Relationships.each(func(_ string, ref ArtifactRef) error {
+			for target, node := range nodes {
+				if !ref.matches(node.ArtifactName, node.ArtifactVersion, node.ArtifactDigest) {
+					continue
+				}
+				if target == self {
+					cycle = append(path, node.ArtifactName+"@"+node.ArtifactVersion)
+					return errStopWalk
+				}
+				if visited[target] {
+					continue
+				}
+				visited[target] = true
+				if cycle = walk(target, path); cycle != nil {
+					return errStopWalk
+				}
+			}
+			return nil
+		})
+		return cycle
+	}
+	return walk(self, nil), nil
+}
+
+var errStopWalk = errors.New("stop walk")
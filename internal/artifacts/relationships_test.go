@@ -0,0 +1,166 @@
+package artifacts
+
+import (
+	"context"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseArtifactRef(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected ArtifactRef
+	}{
+		{"app@1.0.0", ArtifactRef{Name: "app", Version: "1.0.0"}},
+		{" ghcr.io/owner/app@1.0.0 ", ArtifactRef{Name: "ghcr.io/owner/app", Version: "1.0.0"}},
+		{"@scope/package@2.1.0", ArtifactRef{Name: "@scope/package", Version: "2.1.0"}},
+		{"app@" + testDigest, ArtifactRef{Name: "app", Digest: testDigest}},
+		{testDigest, ArtifactRef{Digest: testDigest}},
+	}
+	for _, test := range tests {
+		ref, err := ParseArtifactRef(test.value)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, ref)
+	}
+
+	for _, value := range []string{"app", "app@", "@1.0.0", ""} {
+		_, err := ParseArtifactRef(value)
+		assert.ErrorContains(t, err, "invalid artifact reference")
+	}
+}
+
+func TestParseRelationships(t *testing.T) {
+	relationships, err := ParseRelationships([]string{"chart-image@1.0.0\nsidecar@2.0.0\n"}, nil, []string{testDigest})
+	assert.Nil(t, err)
+	assert.Equal(t, &Relationships{
+		Contains:  []ArtifactRef{{Name: "chart-image", Version: "1.0.0"}, {Name: "sidecar", Version: "2.0.0"}},
+		DependsOn: []ArtifactRef{{Digest: testDigest}},
+	}, relationships)
+
+	relationships, err = ParseRelationships(nil, []string{""}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, relationships)
+
+	_, err = ParseRelationships(nil, []string{"base"}, nil)
+	assert.ErrorContains(t, err, `invalid artifact reference "base"`)
+}
+
+func TestLoadRelationships(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relationships.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte(`
+contains:
+  - name: api
+    version: 1.0.0
+built-from:
+  - digest: `+testDigest+`
+`), 0600))
+	relationships, err := LoadRelationships(path)
+	assert.Nil(t, err)
+	assert.Equal(t, &Relationships{
+		Contains:  []ArtifactRef{{Name: "api", Version: "1.0.0"}},
+		BuiltFrom: []ArtifactRef{{Digest: testDigest}},
+	}, relationships)
+
+	assert.Nil(t, os.WriteFile(path, []byte("requires:\n  - name: api\n"), 0600))
+	_, err = LoadRelationships(path)
+	assert.ErrorContains(t, err, "field requires not found")
+}
+
+func TestCheckRelationships(t *testing.T) {
+
+	t.Run("Event data", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		manifest := filepath.Join(t.TempDir(), "relationships.json")
+		assert.Nil(t, os.WriteFile(manifest, []byte(`{"depends-on": [{"name": "postgres", "version": "16"}]}`), 0600))
+
+		config := &Config{
+			RelationshipsPath: manifest,
+			Relationships:     &Relationships{Contains: []ArtifactRef{{Name: "api", Version: "1.0.0"}}},
+		}
+		_, err := config.Run(context.Background())
+		assert.Nil(t, err)
+
+		var data Output
+		assert.Nil(t, server.Events()[0].DataAs(&data))
+		assert.Equal(t, &Relationships{
+			Contains:  []ArtifactRef{{Name: "api", Version: "1.0.0"}},
+			DependsOn: []ArtifactRef{{Name: "postgres", Version: "16"}},
+		}, data.Relationships)
+	})
+
+	t.Run("No relationships", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+
+		_, err := (&Config{}).Run(context.Background())
+		assert.Nil(t, err)
+		var data map[string]any
+		assert.Nil(t, server.Events()[0].DataAs(&data))
+		assert.NotContains(t, data, "relationships")
+	})
+
+	t.Run("Self-reference", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+
+		config := &Config{Relationships: &Relationships{BuiltFrom: []ArtifactRef{{Name: "testartifact", Version: "1.0.0"}}}}
+		_, err := config.Run(context.Background())
+		assert.EqualError(t, err, "artifact testartifact@1.0.0 cannot reference itself in built-from")
+
+		config = &Config{ArtifactDigest: testDigest, Relationships: &Relationships{Contains: []ArtifactRef{{Digest: testDigest}}}}
+		_, err = config.Run(context.Background())
+		assert.EqualError(t, err, "artifact testartifact@1.0.0 cannot reference itself in contains")
+		assert.Empty(t, server.Events())
+	})
+
+	t.Run("Invalid reference", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+
+		config := &Config{Relationships: &Relationships{DependsOn: []ArtifactRef{{Name: "postgres"}}}}
+		_, err := config.Run(context.Background())
+		assert.EqualError(t, err, `invalid depends-on relationship: artifact reference "postgres" requires a version or a digest`)
+
+		config = &Config{Relationships: &Relationships{DependsOn: []ArtifactRef{{Digest: "sha256:abc"}}}}
+		_, err = config.Run(context.Background())
+		assert.EqualError(t, err, `invalid depends-on relationship: invalid digest "sha256:abc" in artifact reference, expected algorithm:hex`)
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+
+		config := &Config{Relationships: &Relationships{Contains: []ArtifactRef{{Name: "api", Version: "1.0.0"}, {Name: "api", Version: "1.0.0"}}}}
+		_, err := config.Run(context.Background())
+		assert.EqualError(t, err, "artifact api@1.0.0 is listed twice in contains")
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		statePath := filepath.Join(t.TempDir(), "state.json")
+
+		t.Setenv(ArtifactName, "chart")
+		_, err := (&Config{StatePath: statePath, Relationships: &Relationships{Contains: []ArtifactRef{{Name: "api", Version: "1.0.0"}}}}).Run(context.Background())
+		assert.Nil(t, err)
+
+		t.Setenv(ArtifactName, "api")
+		_, err = (&Config{StatePath: statePath, Relationships: &Relationships{DependsOn: []ArtifactRef{{Name: "base", Version: "1.0.0"}}}}).Run(context.Background())
+		assert.Nil(t, err)
+
+		t.Setenv(ArtifactName, "base")
+		_, err = (&Config{StatePath: statePath, Relationships: &Relationships{BuiltFrom: []ArtifactRef{{Name: "chart", Version: "1.0.0"}}}}).Run(context.Background())
+		assert.EqualError(t, err, "relationships of base@1.0.0 form a cycle: base@1.0.0 -> chart@1.0.0 -> api@1.0.0 -> base@1.0.0")
+		assert.Len(t, server.Events(), 2)
+
+		_, err = (&Config{StatePath: statePath, Relationships: &Relationships{BuiltFrom: []ArtifactRef{{Name: "other", Version: "1.0.0"}}}}).Run(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, server.Events(), 3)
+	})
+}
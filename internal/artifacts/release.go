@@ -0,0 +1,150 @@
+package artifacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ReleaseInfo links an artifact to the GitHub release it was published with.
+type ReleaseInfo struct {
+	Id         int64  `json:"id"`
+	Tag        string `json:"tag"`
+	Name       string `json:"name,omitempty"`
+	Url        string `json:"url,omitempty"`
+	Prerelease bool   `json:"prerelease,omitempty"`
+}
+
+type githubRelease struct {
+	Id         int64          `json:"id"`
+	TagName    string         `json:"tag_name"`
+	Name       string         `json:"name"`
+	HtmlUrl    string         `json:"html_url"`
+	Prerelease bool           `json:"prerelease"`
+	Assets     []releaseAsset `json:"assets"`
+}
+
+type releaseAsset struct {
+	Id                 int64  `json:"id"`
+	Name               string `json:"name"`
+	Size               int64  `json:"size"`
+	State              string `json:"state"`
+	Digest             string `json:"digest"`
+	BrowserDownloadUrl string `json:"browser_download_url"`
+}
+
+// RunFromRelease registers each asset of the GitHub release of ReleaseTag, or
+// of the tag in GITHUB_REF, matching Globs. The assets are registered with
+// their download URL, size and digest, the release tag as version and the
+// name read from the file name with FilenameTemplate, or the file name.
+func (config *Config) RunFromRelease(ctx context.Context) ([]*RegistrationResult, error) {
+	artifacts, err := listReleaseAssets(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return config.RunBatch(ctx, artifacts)
+}
+
+func listReleaseAssets(ctx context.Context, config *Config) (artifacts []ArtifactInfo, err error) {
+	ctx, span := startSpan(ctx, StepRelease)
+	defer func() { endSpan(span, err) }()
+
+	logger := stepLogger(StepRelease)
+	if config.GithubToken == "" {
+		return nil, errors.New(GithubToken + " is required to read the release")
+	}
+	repository := os.Getenv(GithubRepository)
+	if repository == "" {
+		return nil, fmt.Errorf(GithubRepository + " is not set in the environment")
+	}
+	tag := config.ReleaseTag
+	if tag == "" {
+		ref := os.Getenv(GithubRef)
+		var found bool
+		if tag, found = strings.CutPrefix(ref, TagRefPrefix); !found {
+			return nil, fmt.Errorf("a release tag is required, %s %q is not a tag", GithubRef, ref)
+		}
+	}
+	span.SetAttributes(attribute.String("release.tag", tag))
+
+	var release githubRelease
+	err = githubGet(ctx, config, fmt.Sprintf("repos/%s/releases/tags/%s", repository, url.PathEscape(tag)), &release)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release %s of %s: %w", tag, repository, err)
+	}
+	config.Release = &ReleaseInfo{
+		Id:         release.Id,
+		Tag:        release.TagName,
+		Name:       release.Name,
+		Url:        release.HtmlUrl,
+		Prerelease: release.Prerelease,
+	}
+
+	globs := config.Globs
+	if len(globs) == 0 {
+		globs = []string{"*"}
+	}
+	filenameRegexp, err := compileFilenameTemplate(firstNonEmpty(config.FilenameTemplate, DefaultFilenameTemplate))
+	if err != nil {
+		return nil, err
+	}
+	for _, asset := range release.Assets {
+		if !matchesGlob(globs, asset.Name) {
+			continue
+		}
+		if asset.State != "uploaded" {
+			logger.Warn("Skipping release asset that is not uploaded", "asset", asset.Name, "state", asset.State)
+			continue
+		}
+		name := asset.Name
+		if match := filenameRegexp.FindStringSubmatch(asset.Name); match != nil {
+			if i := filenameRegexp.SubexpIndex("name"); i > 0 {
+				name = match[i]
+			}
+		}
+		digest := asset.Digest
+		if digest == "" {
+			digest, err = downloadAssetDigest(ctx, config, repository, asset)
+			if err != nil {
+				return nil, err
+			}
+		}
+		logger.Debug("Found release asset", "asset", asset.Name, "name", name, "size", asset.Size, "digest", digest)
+		artifacts = append(artifacts, ArtifactInfo{
+			ArtifactName:    name,
+			ArtifactUrl:     asset.BrowserDownloadUrl,
+			ArtifactVersion: release.TagName,
+			ArtifactDigest:  digest,
+			ArtifactSize:    asset.Size,
+		})
+	}
+	if len(artifacts) == 0 {
+		return nil, fmt.Errorf("release %s of %s has no uploaded assets matching %s", tag, repository, strings.Join(globs, ", "))
+	}
+	span.SetAttributes(attribute.Int("release.assets", len(artifacts)))
+	logger.Info("Found release assets", "release", tag, "assets", len(artifacts))
+	return artifacts, nil
+}
+
+// downloadAssetDigest computes the digest of an asset, for GitHub versions that
+// do not return asset digests.
+func downloadAssetDigest(ctx context.Context, config *Config, repository string, asset releaseAsset) (string, error) {
+	resp, err := githubRequest(ctx, config, fmt.Sprintf("repos/%s/releases/assets/%d", repository, asset.Id), OctetStreamMediaType)
+	if err != nil {
+		return "", fmt.Errorf("failed to download release asset %s: %w", asset.Name, err)
+	}
+	defer resp.Body.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to download release asset %s: %w", asset.Name, err)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
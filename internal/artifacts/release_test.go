@@ -0,0 +1,92 @@
+package artifacts
+
+import (
+	"context"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunFromRelease(t *testing.T) {
+	release := platformtest.Release{
+		Repository: "SrimanPadmanabanCB/gha-action",
+		Id:         42,
+		Tag:        "v2.0.0",
+		Name:       "Release 2.0.0",
+		Assets: []platformtest.ReleaseAsset{
+			{Id: 1, Name: "cli-v2.0.0.tar.gz", Content: "linux", WithDigest: true},
+			{Id: 2, Name: "cli-v2.0.0.zip", Content: "windows"},
+			{Id: 3, Name: "checksums.txt", Content: "checksums", WithDigest: true},
+			{Id: 4, Name: "cli-v2.0.0.deb", Content: "partial", State: "starter"},
+		},
+	}
+	platformConfig := platformtest.Config{GithubToken: "github-token", Releases: []platformtest.Release{release}}
+
+	t.Run("Registers each asset", func(t *testing.T) {
+		server := testserver.New(t, platformConfig)
+		setTestEnv(t, server)
+		t.Setenv(GithubRef, "refs/tags/v2.0.0")
+
+		config := Config{FromRelease: true, GithubToken: "github-token", GithubApiUrl: server.GithubApiUrl()}
+		results, err := config.RunFromRelease(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, results, 3)
+
+		var names, digests []string
+		for i, event := range server.Events() {
+			var data Output
+			assert.Nil(t, event.DataAs(&data))
+			assert.Equal(t, "v2.0.0", data.ArtifactInfo.ArtifactVersion)
+			assert.Equal(t, "https://github.com/SrimanPadmanabanCB/gha-action/releases/download/v2.0.0/"+release.Assets[i].Name, data.ArtifactInfo.ArtifactUrl)
+			assert.Equal(t, int64(len(release.Assets[i].Content)), data.ArtifactInfo.ArtifactSize)
+			assert.Equal(t, &ReleaseInfo{
+				Id:   42,
+				Tag:  "v2.0.0",
+				Name: "Release 2.0.0",
+				Url:  "https://github.com/SrimanPadmanabanCB/gha-action/releases/tag/v2.0.0",
+			}, data.Release)
+			names = append(names, data.ArtifactInfo.ArtifactName)
+			digests = append(digests, data.ArtifactInfo.ArtifactDigest)
+		}
+		assert.Equal(t, []string{"cli", "cli", "checksums.txt"}, names)
+		assert.Equal(t, []string{release.Assets[0].Digest(), release.Assets[1].Digest(), release.Assets[2].Digest()}, digests)
+	})
+
+	t.Run("Release tag and globs", func(t *testing.T) {
+		server := testserver.New(t, platformConfig)
+		setTestEnv(t, server)
+		t.Setenv(GithubRef, "refs/heads/main")
+
+		config := Config{FromRelease: true, ReleaseTag: "v2.0.0", Globs: []string{"*.zip"}, GithubToken: "github-token", GithubApiUrl: server.GithubApiUrl()}
+		results, err := config.RunFromRelease(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, "https://github.com/SrimanPadmanabanCB/gha-action/releases/download/v2.0.0/cli-v2.0.0.zip", server.Artifacts()[0].ArtifactUrl)
+	})
+
+	t.Run("Not a tag", func(t *testing.T) {
+		server := testserver.New(t, platformConfig)
+		setTestEnv(t, server)
+		t.Setenv(GithubRef, "refs/heads/main")
+
+		_, err := (&Config{FromRelease: true, GithubToken: "github-token", GithubApiUrl: server.GithubApiUrl()}).RunFromRelease(context.Background())
+		assert.EqualError(t, err, `a release tag is required, GITHUB_REF "refs/heads/main" is not a tag`)
+	})
+
+	t.Run("Unknown release", func(t *testing.T) {
+		server := testserver.New(t, platformConfig)
+		setTestEnv(t, server)
+
+		config := Config{FromRelease: true, ReleaseTag: "v3.0.0", GithubToken: "github-token", GithubApiUrl: server.GithubApiUrl()}
+		_, err := config.RunFromRelease(context.Background())
+		assert.EqualError(t, err, "failed to read release v3.0.0 of SrimanPadmanabanCB/gha-action: GitHub API - 404 Not Found : Not Found")
+		assert.Empty(t, server.Events())
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		_, err := (&Config{FromRelease: true, ReleaseTag: "v2.0.0"}).RunFromRelease(context.Background())
+		assert.EqualError(t, err, "GITHUB_TOKEN is required to read the release")
+	})
+}
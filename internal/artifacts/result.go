@@ -0,0 +1,102 @@
+package artifacts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// RegistrationResult is the outcome of registering an artifact.
+type RegistrationResult struct {
+	// EventId is the ID of the CloudEvent sent to the platform.
+	EventId string `json:"event_id"`
+	// Status is the HTTP status returned by the platform.
+	Status int `json:"status,omitempty"`
+	// RegistrationId is the ID of the registration, if returned by the platform.
+	RegistrationId string `json:"registration_id,omitempty"`
+	// Links to the registration returned by the platform, keyed by relation.
+	Links map[string]string `json:"links,omitempty"`
+	// Skipped is set when the artifact was already registered in this job.
+	Skipped bool `json:"skipped,omitempty"`
+	// Platform is the os/arch of a platform image of an image index.
+	Platform string `json:"platform,omitempty"`
+	// Platforms are the registrations of the platform images of an image index.
+	Platforms []*RegistrationResult `json:"platforms,omitempty"`
+	// ArtifactName, ArtifactVersion and ArtifactUrl identify the artifact of a
+	// registration of a batch.
+	ArtifactName    string `json:"artifact_name,omitempty"`
+	ArtifactVersion string `json:"artifact_version,omitempty"`
+	ArtifactUrl     string `json:"artifact_url,omitempty"`
+	// Error is set when the registration of an artifact of a batch failed.
+	Error string `json:"error,omitempty"`
+}
+
+type registrationResponse struct {
+	Id    string            `json:"id"`
+	Links map[string]string `json:"links"`
+}
+
+// parseRegistrationResponse reads the registration from a success body. The
+// platform may not return a body, so an empty or unknown body is not an error.
+func parseRegistrationResponse(result *RegistrationResult, body []byte) {
+	var response registrationResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		if len(strings.TrimSpace(string(body))) > 0 {
+			stepLogger(StepSendEvent).Debug("Ignoring unrecognized response body", "body", string(body))
+		}
+		return
+	}
+	result.RegistrationId = response.Id
+	result.Links = response.Links
+}
+
+// WriteGithubOutputs appends the result as step outputs to the GITHUB_OUTPUT
+// file. Nothing is written outside of GitHub Actions.
+func (result *RegistrationResult) WriteGithubOutputs() error {
+	return writeGithubOutputs([]githubOutput{
+		{"event-id", result.EventId},
+		{"registration-id", result.RegistrationId},
+		{"registration-url", result.Links["self"]},
+		{"skipped", fmt.Sprint(result.Skipped)},
+	})
+}
+
+// WriteBatchGithubOutputs appends the results of a batch as a JSON array to
+// the registrations step output.
+func WriteBatchGithubOutputs(results []*RegistrationResult) error {
+	registrations, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("failed to encode registrations: %w", err)
+	}
+	return writeGithubOutputs([]githubOutput{{"registrations", string(registrations)}})
+}
+
+type githubOutput struct{ name, value string }
+
+func writeGithubOutputs(outputs []githubOutput) error {
+	path := os.Getenv(GithubOutput)
+	if path == "" {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", GithubOutput, err)
+	}
+	defer file.Close()
+
+	// The values come from the platform, so they are written between random
+	// delimiters: a newline in a value cannot start another output.
+	for _, output := range outputs {
+		delimiter := "ghadelimiter_" + uuid.NewString()
+		if strings.Contains(output.value, delimiter) {
+			return fmt.Errorf("output %s contains its delimiter", output.name)
+		}
+		if _, err := fmt.Fprintf(file, "%s<<%s\n%s\n%s\n", output.name, delimiter, output.value, delimiter); err != nil {
+			return fmt.Errorf("failed to write %s: %w", GithubOutput, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package artifacts
+
+import (
+	"context"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrationResult(t *testing.T) {
+
+	t.Run("Registration returned", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		result, err := (&Config{}).Run(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, server.Events()[0].ID(), result.EventId)
+		assert.Equal(t, http.StatusOK, result.Status)
+		assert.Equal(t, server.Artifacts()[0].Id, result.RegistrationId)
+		assert.Empty(t, result.Links)
+		assert.False(t, result.Skipped)
+	})
+
+	for _, tc := range []struct {
+		name           string
+		fault          platformtest.Fault
+		registrationId string
+	}{
+		{"Created", platformtest.Fault{Status: http.StatusCreated, Body: `{"id": "reg-1", "links": {"self": "https://api.cloudbees.io/v3/artifacts/reg-1"}}`}, "reg-1"},
+		{"Accepted without body", platformtest.Fault{Status: http.StatusAccepted}, ""},
+		{"No content", platformtest.Fault{Status: http.StatusNoContent}, ""},
+		{"Unrecognized body", platformtest.Fault{Status: http.StatusOK, Body: "OK"}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+				platformtest.EventsEndpoint: tc.fault,
+			}})
+			setTestEnv(t, server)
+			result, err := (&Config{}).Run(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, tc.fault.Status, result.Status)
+			assert.Equal(t, tc.registrationId, result.RegistrationId)
+			assert.NotEmpty(t, result.EventId)
+		})
+	}
+
+	t.Run("Redirect is an error", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.EventsEndpoint: {Status: http.StatusNotModified},
+		}})
+		setTestEnv(t, server)
+		result, err := (&Config{}).Run(context.Background())
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "error sending CloudEvent to platform - 304 Not Modified : \n  request id: ")
+	})
+}
+
+// readGithubOutputs parses the GITHUB_OUTPUT file as the runner does.
+func readGithubOutputs(t *testing.T, path string) map[string]string {
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	outputs := map[string]string{}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		if name, delimiter, found := strings.Cut(lines[i], "<<"); found {
+			var value []string
+			for i++; i < len(lines) && lines[i] != delimiter; i++ {
+				value = append(value, lines[i])
+			}
+			assert.Less(t, i, len(lines), "missing delimiter of output %s", name)
+			outputs[name] = strings.Join(value, "\n")
+			continue
+		}
+		name, value, _ := strings.Cut(lines[i], "=")
+		outputs[name] = value
+	}
+	return outputs
+}
+
+func TestWriteGithubOutputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output")
+	t.Setenv(GithubOutput, path)
+	assert.Nil(t, os.WriteFile(path, []byte("previous=value\n"), 0644))
+
+	result := &RegistrationResult{
+		EventId:        "event",
+		RegistrationId: "reg-1",
+		Links:          map[string]string{"self": "https://api.cloudbees.io/v3/artifacts/reg-1"},
+	}
+	assert.Nil(t, result.WriteGithubOutputs())
+	assert.Equal(t, map[string]string{
+		"previous":         "value",
+		"event-id":         "event",
+		"registration-id":  "reg-1",
+		"registration-url": "https://api.cloudbees.io/v3/artifacts/reg-1",
+		"skipped":          "false",
+	}, readGithubOutputs(t, path))
+
+	t.Run("Multiline value", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "output")
+		t.Setenv(GithubOutput, path)
+		result := &RegistrationResult{EventId: "event", RegistrationId: "reg-1\nskipped=true"}
+		assert.Nil(t, result.WriteGithubOutputs())
+		outputs := readGithubOutputs(t, path)
+		assert.Equal(t, "reg-1\nskipped=true", outputs["registration-id"])
+		assert.Equal(t, "false", outputs["skipped"])
+	})
+
+	t.Setenv(GithubOutput, "")
+	assert.Nil(t, result.WriteGithubOutputs())
+}
+
+func TestWriteBatchGithubOutputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output")
+	t.Setenv(GithubOutput, path)
+
+	results := []*RegistrationResult{{EventId: "event-1", RegistrationId: "reg-1"}, nil}
+	assert.Nil(t, WriteBatchGithubOutputs(results))
+	assert.Equal(t, map[string]string{
+		"registrations": `[{"event_id":"event-1","registration_id":"reg-1"},null]`,
+	}, readGithubOutputs(t, path))
+}
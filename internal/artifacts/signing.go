@@ -0,0 +1,229 @@
+package artifacts
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// LoadSigningKey reads an ed25519 or ECDSA private key from PEM. The value is
+// either the PEM content itself or the path of a file holding it.
+func LoadSigningKey(value string) (crypto.Signer, error) {
+	block, err := readPemBlock(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported signing key PEM type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %w", err)
+	}
+	switch signer := key.(type) {
+	case ed25519.PrivateKey:
+		return signer, nil
+	case *ecdsa.PrivateKey:
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T, expected ed25519 or ECDSA", key)
+	}
+}
+
+// LoadPublicKey reads an ed25519 or ECDSA public key from PEM. The value is
+// either the PEM content itself or the path of a file holding it.
+func LoadPublicKey(value string) (crypto.PublicKey, error) {
+	block, err := readPemBlock(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("unsupported public key PEM type %q", block.Type)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T, expected ed25519 or ECDSA", key)
+	}
+}
+
+func readPemBlock(value string) (*pem.Block, error) {
+	data := []byte(value)
+	if !strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN") {
+		var err error
+		data, err = os.ReadFile(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	return block, nil
+}
+
+// signCloudEvent signs the canonicalized event data and records the detached
+// signature, algorithm and key ID as CloudEvent extensions.
+func signCloudEvent(cloudEvent *cloudevents.Event, signer crypto.Signer) error {
+	payload, err := canonicalEventData(*cloudEvent)
+	if err != nil {
+		return err
+	}
+	algorithm, err := signatureAlgorithm(signer.Public())
+	if err != nil {
+		return err
+	}
+	keyId, err := publicKeyId(signer.Public())
+	if err != nil {
+		return err
+	}
+
+	var signature []byte
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		signature, err = signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	} else {
+		hash := signatureHash(algorithm)
+		signature, err = signer.Sign(rand.Reader, digestPayload(hash, payload), hash)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to sign event data: %w", err)
+	}
+
+	cloudEvent.SetExtension(SignatureExtension, base64.StdEncoding.EncodeToString(signature))
+	cloudEvent.SetExtension(SignatureAlgExtension, algorithm)
+	cloudEvent.SetExtension(SignatureKeyIdExtension, keyId)
+	return nil
+}
+
+// VerifyCloudEvent checks the detached signature of a signed CloudEvent
+// against the given public key.
+func VerifyCloudEvent(cloudEvent cloudevents.Event, publicKey crypto.PublicKey) error {
+	extensions := cloudEvent.Extensions()
+	encodedSignature, _ := extensions[SignatureExtension].(string)
+	if encodedSignature == "" {
+		return errors.New("event is not signed")
+	}
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return fmt.Errorf("invalid event signature encoding: %w", err)
+	}
+
+	keyId, err := publicKeyId(publicKey)
+	if err != nil {
+		return err
+	}
+	if eventKeyId, _ := extensions[SignatureKeyIdExtension].(string); eventKeyId != keyId {
+		return fmt.Errorf("event was signed with key %s, not %s", eventKeyId, keyId)
+	}
+	algorithm, err := signatureAlgorithm(publicKey)
+	if err != nil {
+		return err
+	}
+	if eventAlgorithm, _ := extensions[SignatureAlgExtension].(string); eventAlgorithm != algorithm {
+		return fmt.Errorf("event signature algorithm %s does not match key algorithm %s", eventAlgorithm, algorithm)
+	}
+
+	payload, err := canonicalEventData(cloudEvent)
+	if err != nil {
+		return err
+	}
+	var valid bool
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, payload, signature)
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digestPayload(signatureHash(algorithm), payload), signature)
+	}
+	if !valid {
+		return errors.New("event signature verification failed")
+	}
+	return nil
+}
+
+// canonicalEventData re-encodes the event data as compact JSON with sorted
+// object keys so that the signature does not depend on formatting.
+func canonicalEventData(cloudEvent cloudevents.Event) ([]byte, error) {
+	decoder := json.NewDecoder(strings.NewReader(string(cloudEvent.Data())))
+	decoder.UseNumber()
+	var data any
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to canonicalize event data: %w", err)
+	}
+	canonical, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to canonicalize event data: %w", err)
+	}
+	return canonical, nil
+}
+
+// publicKeyId is the hex encoded SHA-256 of the DER encoded public key.
+func publicKeyId(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func signatureAlgorithm(publicKey crypto.PublicKey) (string, error) {
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		return SignatureAlgEdDSA, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return SignatureAlgES256, nil
+		case elliptic.P384():
+			return SignatureAlgES384, nil
+		case elliptic.P521():
+			return SignatureAlgES512, nil
+		}
+		return "", fmt.Errorf("unsupported ECDSA curve %s", key.Curve.Params().Name)
+	default:
+		return "", fmt.Errorf("unsupported key type %T", publicKey)
+	}
+}
+
+func signatureHash(algorithm string) crypto.Hash {
+	switch algorithm {
+	case SignatureAlgES384:
+		return crypto.SHA384
+	case SignatureAlgES512:
+		return crypto.SHA512
+	default:
+		return crypto.SHA256
+	}
+}
+
+func digestPayload(hash crypto.Hash, payload []byte) []byte {
+	hasher := hash.New()
+	hasher.Write(payload)
+	return hasher.Sum(nil)
+}
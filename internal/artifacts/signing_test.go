@@ -0,0 +1,104 @@
+package artifacts
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func encodePrivateKey(t *testing.T, key crypto.Signer) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.Nil(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func encodePublicKey(t *testing.T, key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	assert.Nil(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func signedTestEvent(t *testing.T, signer crypto.Signer) cloudevents.Event {
+	config := provenanceConfig()
+	cloudEvent, err := prepareCloudEvent(config, prepareCloudEventData(config))
+	assert.Nil(t, err)
+	assert.Nil(t, signCloudEvent(&cloudEvent, signer))
+	return cloudEvent
+}
+
+func TestSignCloudEvent(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+	for name, key := range map[string]crypto.Signer{SignatureAlgEdDSA: edKey, SignatureAlgES384: ecKey} {
+		t.Run("Sign and verify "+name, func(t *testing.T) {
+			signer, err := LoadSigningKey(encodePrivateKey(t, key))
+			assert.Nil(t, err)
+			cloudEvent := signedTestEvent(t, signer)
+			assert.Equal(t, name, cloudEvent.Extensions()[SignatureAlgExtension])
+
+			publicKey, err := LoadPublicKey(encodePublicKey(t, key.Public()))
+			assert.Nil(t, err)
+			assert.Nil(t, VerifyCloudEvent(cloudEvent, publicKey))
+		})
+	}
+
+	t.Run("Verify stored event", func(t *testing.T) {
+		cloudEvent := signedTestEvent(t, edKey)
+		path := filepath.Join(t.TempDir(), "event.json")
+		assert.Nil(t, writeCloudEvent(cloudEvent, path))
+
+		var stored cloudevents.Event
+		data, err := os.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Nil(t, json.Unmarshal(data, &stored))
+		assert.Nil(t, VerifyCloudEvent(stored, edKey.Public()))
+	})
+
+	t.Run("Tampered data", func(t *testing.T) {
+		cloudEvent := signedTestEvent(t, edKey)
+		output := prepareCloudEventData(provenanceConfig())
+		output.ArtifactInfo.ArtifactUrl = "https://evil.com"
+		assert.Nil(t, cloudEvent.SetData(ContentTypeJson, output))
+		err := VerifyCloudEvent(cloudEvent, edKey.Public())
+		assert.NotNil(t, err)
+		assert.Equal(t, "event signature verification failed", err.Error())
+	})
+
+	t.Run("Wrong key", func(t *testing.T) {
+		cloudEvent := signedTestEvent(t, edKey)
+		otherKey, _, _ := ed25519.GenerateKey(rand.Reader)
+		err := VerifyCloudEvent(cloudEvent, otherKey)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "event was signed with key")
+	})
+
+	t.Run("Unsigned event", func(t *testing.T) {
+		config := provenanceConfig()
+		cloudEvent, _ := prepareCloudEvent(config, prepareCloudEventData(config))
+		err := VerifyCloudEvent(cloudEvent, edKey.Public())
+		assert.NotNil(t, err)
+		assert.Equal(t, "event is not signed", err.Error())
+	})
+
+	t.Run("Invalid signing key", func(t *testing.T) {
+		_, err := LoadSigningKey(encodePublicKey(t, edKey.Public()))
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "unsupported signing key PEM type")
+
+		_, err = LoadSigningKey(filepath.Join(t.TempDir(), "missing.pem"))
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "failed to read signing key")
+	})
+}
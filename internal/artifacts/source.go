@@ -0,0 +1,124 @@
+package artifacts
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// SourceInfo describes the source revision the artifact was built from.
+type SourceInfo struct {
+	Repository    string `json:"repository,omitempty"`
+	Commit        string `json:"commit,omitempty"`
+	Ref           string `json:"ref,omitempty"`
+	Branch        string `json:"branch,omitempty"`
+	Tag           string `json:"tag,omitempty"`
+	PullRequest   int    `json:"pull_request,omitempty"`
+	CommitMessage string `json:"commit_message,omitempty"`
+	Author        string `json:"author,omitempty"`
+	Actor         string `json:"actor,omitempty"`
+}
+
+// workflowEvent holds the fields of the GITHUB_EVENT_PATH payload used for
+// the source info, from push and pull_request events.
+type workflowEvent struct {
+	Number      int `json:"number"`
+	PullRequest struct {
+		Number int `json:"number"`
+	} `json:"pull_request"`
+	HeadCommit struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		} `json:"author"`
+	} `json:"head_commit"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+// getSourceInfo collects the source revision from the workflow environment
+// and event payload, falling back to the local git repository for the commit
+// message and author when enabled. The local commit is only used when it is
+// the commit of the workflow. It returns nil outside of a workflow.
+func getSourceInfo(config *Config) *SourceInfo {
+	logger := stepLogger(StepSource)
+	ref := os.Getenv(GithubRef)
+	info := &SourceInfo{
+		Repository: config.GhaRepository,
+		Commit:     os.Getenv(GithubSha),
+		Ref:        ref,
+		Branch:     os.Getenv(GithubHeadRef),
+		Actor:      os.Getenv(GithubActor),
+	}
+	if branch, found := strings.CutPrefix(ref, "refs/heads/"); found && info.Branch == "" {
+		info.Branch = branch
+	}
+	if tag, found := strings.CutPrefix(ref, "refs/tags/"); found {
+		info.Tag = tag
+	}
+	if number, found := strings.CutPrefix(ref, "refs/pull/"); found {
+		info.PullRequest, _ = strconv.Atoi(strings.TrimSuffix(number, "/merge"))
+	}
+
+	if path := os.Getenv(GithubEventPath); path != "" {
+		var event workflowEvent
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(data, &event)
+		}
+		if err != nil {
+			logger.Warn("Failed to read the workflow event payload", "path", path, "error", err)
+		} else {
+			info.PullRequest = max(info.PullRequest, event.PullRequest.Number, event.Number)
+			info.CommitMessage = strings.TrimSpace(event.HeadCommit.Message)
+			info.Author = formatAuthor(event.HeadCommit.Author.Name, event.HeadCommit.Author.Email)
+			info.Actor = firstNonEmpty(info.Actor, event.Sender.Login)
+		}
+	}
+
+	if config.GitMetadata && (info.CommitMessage == "" || info.Author == "" || info.Commit == "") {
+		dir := firstNonEmpty(os.Getenv(GithubWorkspace), ".")
+		commit, message, author := gitHeadCommit(dir)
+		switch {
+		case commit == "":
+			logger.Debug("No local git commit found", "dir", dir)
+		case info.Commit != "" && commit != info.Commit:
+			logger.Warn("Ignoring the local git commit, which is not the commit of the workflow", "dir", dir, "commit", commit, "workflow_commit", info.Commit)
+		default:
+			info.Commit = commit
+			info.CommitMessage = firstNonEmpty(info.CommitMessage, message)
+			info.Author = firstNonEmpty(info.Author, author)
+		}
+	}
+
+	if *info == (SourceInfo{Repository: config.GhaRepository}) {
+		return nil
+	}
+	return info
+}
+
+// gitHeadCommit returns the SHA, message and author of HEAD in dir.
+func gitHeadCommit(dir string) (string, string, string) {
+	command := exec.Command("git", "log", "-1", "--format=%H%x00%an%x00%ae%x00%B", "HEAD")
+	command.Dir = dir
+	out, err := command.Output()
+	if err != nil {
+		return "", "", ""
+	}
+	fields := strings.SplitN(string(out), "\x00", 4)
+	if len(fields) != 4 {
+		return "", "", ""
+	}
+	return fields[0], strings.TrimSpace(fields[3]), formatAuthor(fields[1], fields[2])
+}
+
+func formatAuthor(name, email string) string {
+	if email == "" {
+		return name
+	}
+	return strings.TrimSpace(name + " <" + email + ">")
+}
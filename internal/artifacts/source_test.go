@@ -0,0 +1,127 @@
+package artifacts
+
+import (
+	"context"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setSourceEnv(t *testing.T, sha, ref, headRef, eventPayload string) {
+	t.Setenv(GithubSha, sha)
+	t.Setenv(GithubRef, ref)
+	t.Setenv(GithubHeadRef, headRef)
+	t.Setenv(GithubActor, "")
+	t.Setenv(GithubWorkspace, "")
+	t.Setenv(GithubEventPath, "")
+	if eventPayload != "" {
+		dir := t.TempDir()
+		writeBuildFile(t, dir, "event.json", eventPayload)
+		t.Setenv(GithubEventPath, dir+"/event.json")
+	}
+}
+
+func TestGetSourceInfo(t *testing.T) {
+	config := &Config{GhaRepository: "owner/repo"}
+
+	t.Run("Push", func(t *testing.T) {
+		setSourceEnv(t, "a1b2c3", "refs/heads/main", "", `{
+			"head_commit": {"message": "Fix build\n", "author": {"name": "Jane Doe", "email": "jane@example.com"}},
+			"sender": {"login": "jdoe"}
+		}`)
+		assert.Equal(t, &SourceInfo{
+			Repository:    "owner/repo",
+			Commit:        "a1b2c3",
+			Ref:           "refs/heads/main",
+			Branch:        "main",
+			CommitMessage: "Fix build",
+			Author:        "Jane Doe <jane@example.com>",
+			Actor:         "jdoe",
+		}, getSourceInfo(config))
+	})
+
+	t.Run("Pull request", func(t *testing.T) {
+		setSourceEnv(t, "d4e5f6", "refs/pull/42/merge", "feature/login", `{"number": 42, "pull_request": {"number": 42}, "sender": {"login": "jdoe"}}`)
+		t.Setenv(GithubActor, "octocat")
+		assert.Equal(t, &SourceInfo{
+			Repository:  "owner/repo",
+			Commit:      "d4e5f6",
+			Ref:         "refs/pull/42/merge",
+			Branch:      "feature/login",
+			PullRequest: 42,
+			Actor:       "octocat",
+		}, getSourceInfo(config))
+	})
+
+	t.Run("Tag", func(t *testing.T) {
+		setSourceEnv(t, "a1b2c3", "refs/tags/v1.0.0", "", "")
+		info := getSourceInfo(config)
+		assert.Equal(t, "v1.0.0", info.Tag)
+		assert.Empty(t, info.Branch)
+	})
+
+	t.Run("Invalid event payload", func(t *testing.T) {
+		setSourceEnv(t, "a1b2c3", "refs/heads/main", "", "{")
+		info := getSourceInfo(config)
+		assert.Equal(t, "a1b2c3", info.Commit)
+		assert.Empty(t, info.Actor)
+	})
+
+	t.Run("Outside of a workflow", func(t *testing.T) {
+		setSourceEnv(t, "", "", "", "")
+		assert.Nil(t, getSourceInfo(config))
+	})
+
+	t.Run("Local git repository", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git is not installed")
+		}
+		dir := t.TempDir()
+		for _, args := range [][]string{
+			{"init", "-q"},
+			{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "Add feature\n\nDetails"},
+		} {
+			command := exec.Command("git", args...)
+			command.Dir = dir
+			assert.Nil(t, command.Run())
+		}
+		out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+		assert.Nil(t, err)
+
+		setSourceEnv(t, "", "refs/heads/main", "", "")
+		t.Setenv(GithubWorkspace, dir)
+		assert.Empty(t, getSourceInfo(config).CommitMessage)
+
+		info := getSourceInfo(&Config{GhaRepository: "owner/repo", GitMetadata: true})
+		assert.Equal(t, strings.TrimSpace(string(out)), info.Commit)
+		assert.Equal(t, "Add feature\n\nDetails", info.CommitMessage)
+		assert.Equal(t, "test <test@example.com>", info.Author)
+
+		setSourceEnv(t, strings.TrimSpace(string(out)), "refs/heads/main", "", "")
+		t.Setenv(GithubWorkspace, dir)
+		info = getSourceInfo(&Config{GhaRepository: "owner/repo", GitMetadata: true})
+		assert.Equal(t, "Add feature\n\nDetails", info.CommitMessage)
+
+		setSourceEnv(t, "a1b2c3", "refs/heads/main", "", "")
+		t.Setenv(GithubWorkspace, dir)
+		info = getSourceInfo(&Config{GhaRepository: "owner/repo", GitMetadata: true})
+		assert.Equal(t, "a1b2c3", info.Commit)
+		assert.Empty(t, info.CommitMessage)
+		assert.Empty(t, info.Author)
+	})
+
+	t.Run("Event data", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		setSourceEnv(t, "a1b2c3", "refs/heads/main", "", "")
+		_, err := (&Config{}).Run(context.Background())
+		assert.Nil(t, err)
+		var data Output
+		assert.Nil(t, server.Events()[0].DataAs(&data))
+		assert.Equal(t, &SourceInfo{Repository: "SrimanPadmanabanCB/gha-action", Commit: "a1b2c3", Ref: "refs/heads/main", Branch: "main"}, data.SourceInfo)
+	})
+}
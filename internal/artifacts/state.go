@@ -0,0 +1,148 @@
+package artifacts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var idempotencyNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://cloudbees.io/"+BuildArtifactType))
+
+// RegistrationState records the artifacts registered by earlier invocations in
+// the same job, keyed by idempotency key.
+type RegistrationState struct {
+	Registrations map[string]StateEntry `json:"registrations"`
+}
+
+// StateEntry is a registration recorded in the state file.
+type StateEntry struct {
+	EventId         string         `json:"event_id"`
+	RegistrationId  string         `json:"registration_id,omitempty"`
+	ArtifactName    string         `json:"artifact_name"`
+	ArtifactVersion string         `json:"artifact_version"`
+	ArtifactDigest  string         `json:"artifact_digest,omitempty"`
+	Relationships   *Relationships `json:"relationships,omitempty"`
+	RegisteredAt    time.Time      `json:"registered_at"`
+}
+
+// idempotencyKey identifies the registration of an artifact by a workflow run.
+// Unlike the event subject it leaves out the run attempt, so a re-run of the
+// job maps to the same key.
+func idempotencyKey(config *Config) string {
+	return strings.Join([]string{
+		config.GhaWorkflowRef,
+		config.GhaRunId,
+		config.GhaRunNumber,
+		config.ArtifactName,
+		config.ArtifactVersion,
+		config.ArtifactUrl,
+		config.ArtifactType,
+		config.ArtifactDigest,
+	}, "|")
+}
+
+// getEventId returns a random event ID, or one derived from the idempotency
+// key when the registration is idempotent.
+func getEventId(config *Config) string {
+	if config.Idempotent {
+		return uuid.NewSHA1(idempotencyNamespace, []byte(idempotencyKey(config))).String()
+	}
+	return uuid.NewString()
+}
+
+// getStatePath returns the state file, by default in the job's RUNNER_TEMP.
+// Empty means that no state is kept.
+func getStatePath(config *Config) string {
+	if config.StatePath != "" {
+		return config.StatePath
+	}
+	if runnerTemp := os.Getenv(RunnerTemp); runnerTemp != "" {
+		return filepath.Join(runnerTemp, StateFileName)
+	}
+	return ""
+}
+
+// stateMu serializes the updates of the state file by concurrent
+// registrations of a batch.
+var stateMu sync.Mutex
+
+func loadState(path string) (*RegistrationState, error) {
+	state := &RegistrationState{Registrations: map[string]StateEntry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if state.Registrations == nil {
+		state.Registrations = map[string]StateEntry{}
+	}
+	return state, nil
+}
+
+// readState loads the state file while no registration of a batch updates it.
+func readState(path string) (*RegistrationState, error) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	return loadState(path)
+}
+
+func (state *RegistrationState) save(path string) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state file: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write state file %s: %w", path, err)
+	}
+	return nil
+}
+
+// registeredEvent returns an earlier registration of the artifact in the same
+// job, if any.
+func registeredEvent(config *Config) (StateEntry, bool, error) {
+	path := getStatePath(config)
+	if path == "" {
+		return StateEntry{}, false, nil
+	}
+	state, err := readState(path)
+	if err != nil {
+		return StateEntry{}, false, err
+	}
+	entry, ok := state.Registrations[idempotencyKey(config)]
+	return entry, ok, nil
+}
+
+func recordRegistration(config *Config, result *RegistrationResult) error {
+	path := getStatePath(config)
+	if path == "" {
+		return nil
+	}
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	state, err := loadState(path)
+	if err != nil {
+		return err
+	}
+	state.Registrations[idempotencyKey(config)] = StateEntry{
+		EventId:         result.EventId,
+		RegistrationId:  result.RegistrationId,
+		ArtifactName:    config.ArtifactName,
+		ArtifactVersion: config.ArtifactVersion,
+		ArtifactDigest:  config.ArtifactDigest,
+		Relationships:   config.Relationships,
+		RegisteredAt:    time.Now().UTC(),
+	}
+	return state.save(path)
+}
@@ -0,0 +1,124 @@
+package artifacts
+
+import (
+	"context"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEventId(t *testing.T) {
+	config := Config{
+		GhaWorkflowRef:  "owner/repo/.github/workflows/build.yml@refs/heads/main",
+		GhaRunId:        "123456789",
+		GhaRunAttempt:   "1",
+		GhaRunNumber:    "123",
+		ArtifactName:    "testartifact",
+		ArtifactVersion: "1.0.0",
+		ArtifactDigest:  testDigest,
+		Idempotent:      true,
+	}
+	eventId := getEventId(&config)
+	assert.Equal(t, eventId, getEventId(&config))
+
+	rerun := config
+	rerun.GhaRunAttempt = "2"
+	assert.Equal(t, eventId, getEventId(&rerun))
+
+	otherVersion := config
+	otherVersion.ArtifactVersion = "1.0.1"
+	assert.NotEqual(t, eventId, getEventId(&otherVersion))
+
+	otherRegistry := config
+	otherRegistry.ArtifactUrl = "https://mirror.test.com"
+	assert.NotEqual(t, eventId, getEventId(&otherRegistry))
+
+	otherType := config
+	otherType.ArtifactType = "docker"
+	assert.NotEqual(t, eventId, getEventId(&otherType))
+
+	config.Idempotent = false
+	assert.NotEqual(t, getEventId(&config), getEventId(&config))
+}
+
+func TestRegistrationState(t *testing.T) {
+
+	t.Run("Repeated invocation", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		t.Setenv(RunnerTemp, t.TempDir())
+
+		registered, err := (&Config{}).Run(context.Background())
+		assert.Nil(t, err)
+		skipped, err := (&Config{}).Run(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, server.Events(), 1)
+		assert.True(t, skipped.Skipped)
+		assert.Equal(t, registered.EventId, skipped.EventId)
+		assert.Equal(t, registered.RegistrationId, skipped.RegistrationId)
+		assert.FileExists(t, filepath.Join(os.Getenv(RunnerTemp), StateFileName))
+
+		t.Setenv(ArtifactVersion, "1.0.1")
+		_, err = (&Config{}).Run(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, server.Events(), 2)
+	})
+
+	t.Run("Two registries", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		t.Setenv(RunnerTemp, t.TempDir())
+
+		first, err := (&Config{Idempotent: true}).Run(context.Background())
+		assert.Nil(t, err)
+		t.Setenv(ArtifactUrl, "https://mirror.test.com")
+		second, err := (&Config{Idempotent: true}).Run(context.Background())
+		assert.Nil(t, err)
+		assert.False(t, second.Skipped)
+		assert.NotEqual(t, first.EventId, second.EventId)
+		assert.Len(t, server.Events(), 2)
+	})
+
+	t.Run("Force", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		statePath := filepath.Join(t.TempDir(), "state.json")
+
+		_, err := (&Config{StatePath: statePath}).Run(context.Background())
+		assert.Nil(t, err)
+		_, err = (&Config{StatePath: statePath, Force: true}).Run(context.Background())
+		assert.Nil(t, err)
+		assert.Len(t, server.Events(), 2)
+		assert.NotEqual(t, server.Events()[0].ID(), server.Events()[1].ID())
+	})
+
+	t.Run("Idempotent re-run", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+
+		_, err := (&Config{Idempotent: true}).Run(context.Background())
+		assert.Nil(t, err)
+		t.Setenv(GithubRunAttempt, "2")
+		_, err = (&Config{Idempotent: true}).Run(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, 2, server.Requests(platformtest.EventsEndpoint))
+		assert.Len(t, server.Events(), 1)
+		assert.Equal(t, server.Events()[0].ID(), server.Header(platformtest.EventsEndpoint).Get(IdempotencyKeyHeader))
+	})
+
+	t.Run("Invalid state file", func(t *testing.T) {
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+		statePath := filepath.Join(t.TempDir(), "state.json")
+		assert.Nil(t, os.WriteFile(statePath, []byte("{"), 0600))
+
+		_, err := (&Config{StatePath: statePath}).Run(context.Background())
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "failed to parse state file "+statePath)
+		assert.Empty(t, server.Events())
+	})
+}
@@ -0,0 +1,127 @@
+package artifacts
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// CloudbeesToken is a short-lived CloudBees access token exchanged for the
+// OIDC token of the job.
+type CloudbeesToken struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// ExecCredential is the exec credential format read by kubectl and other
+// clients running a credential helper.
+type ExecCredential struct {
+	ApiVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Status     ExecCredentialStatus `json:"status"`
+}
+
+type ExecCredentialStatus struct {
+	Token               string     `json:"token"`
+	ExpirationTimestamp *time.Time `json:"expirationTimestamp,omitempty"`
+}
+
+// Token fetches the OIDC token of the job and exchanges it for a CloudBees
+// access token, so that other steps can call the CloudBees API.
+func (config *Config) Token(ctx context.Context) (*CloudbeesToken, error) {
+	if config.CloudBeesApiUrl == "" {
+		config.CloudBeesApiUrl = os.Getenv(CloudbeesApiUrl)
+		if config.CloudBeesApiUrl == "" {
+			return nil, fmt.Errorf(CloudbeesApiUrl + " is not set in the environment")
+		}
+	}
+	loadServerUrl(config)
+	accessToken, err := authenticate(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	token := &CloudbeesToken{Token: accessToken}
+	if claims, err := decodeClaims(accessToken); err == nil && claims.Expiry != 0 {
+		token.ExpiresAt = time.Unix(claims.Expiry, 0).UTC()
+	}
+	return token, nil
+}
+
+// ExecCredential returns the token in the exec credential format.
+func (token *CloudbeesToken) ExecCredential() ExecCredential {
+	credential := ExecCredential{
+		ApiVersion: ExecCredentialApiVersion,
+		Kind:       ExecCredentialKind,
+		Status:     ExecCredentialStatus{Token: token.Token},
+	}
+	if !token.ExpiresAt.IsZero() {
+		credential.Status.ExpirationTimestamp = &token.ExpiresAt
+	}
+	return credential
+}
+
+// WriteGithubOutputs masks the token in the job logs through the workflow
+// command written to w, then appends it to the token step output.
+func (token *CloudbeesToken) WriteGithubOutputs(w io.Writer) error {
+	if os.Getenv(GithubOutput) == "" {
+		return fmt.Errorf(GithubOutput + " is not set in the environment")
+	}
+	if _, err := fmt.Fprintf(w, "::add-mask::%s\n", token.Token); err != nil {
+		return fmt.Errorf("failed to mask the token: %w", err)
+	}
+	outputs := []githubOutput{{"token", token.Token}}
+	if !token.ExpiresAt.IsZero() {
+		outputs = append(outputs, githubOutput{"expires-at", token.ExpiresAt.Format(time.RFC3339)})
+	}
+	return writeGithubOutputs(outputs)
+}
+
+type jwtClaims struct {
+	Issuer     string   `json:"iss"`
+	Subject    string   `json:"sub"`
+	Audience   audience `json:"aud"`
+	Expiry     int64    `json:"exp"`
+	Repository string   `json:"repository"`
+	Ref        string   `json:"ref"`
+}
+
+// audience is the aud claim, a string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+// decodeClaims returns the claims of a JWT. The signature is verified by the
+// platform.
+func decodeClaims(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed OIDC token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed OIDC token payload: %w", err)
+	}
+	claims := &jwtClaims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, fmt.Errorf("malformed OIDC token payload: %w", err)
+	}
+	return claims, nil
+}
@@ -0,0 +1,59 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken(t *testing.T) {
+	server := testserver.New(t, platformtest.Config{})
+	setTestEnv(t, server)
+
+	token, err := (&Config{}).Token(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(token.Token, "mock-cbp-token-"))
+	assert.True(t, token.ExpiresAt.IsZero())
+	assert.NoFileExists(t, "access_token.txt")
+	assert.Empty(t, server.Events())
+
+	t.Run("Missing url", func(t *testing.T) {
+		t.Setenv(CloudbeesApiUrl, "")
+		_, err := (&Config{}).Token(context.Background())
+		assert.Equal(t, CloudbeesApiUrl+" is not set in the environment", err.Error())
+	})
+}
+
+func TestTokenGithubOutputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output")
+	t.Setenv(GithubOutput, path)
+	token := &CloudbeesToken{Token: "secret", ExpiresAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	var stdout bytes.Buffer
+	assert.Nil(t, token.WriteGithubOutputs(&stdout))
+	assert.Equal(t, "::add-mask::secret\n", stdout.String())
+	assert.Equal(t, map[string]string{"token": "secret", "expires-at": "2025-01-02T03:04:05Z"}, readGithubOutputs(t, path))
+
+	t.Setenv(GithubOutput, "")
+	stdout.Reset()
+	assert.Equal(t, GithubOutput+" is not set in the environment", token.WriteGithubOutputs(&stdout).Error())
+	assert.Empty(t, stdout.String())
+}
+
+func TestExecCredential(t *testing.T) {
+	credential, err := json.Marshal((&CloudbeesToken{Token: "secret"}).ExecCredential())
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"apiVersion": "client.authentication.k8s.io/v1", "kind": "ExecCredential", "status": {"token": "secret"}}`, string(credential))
+
+	credential, err = json.Marshal((&CloudbeesToken{Token: "secret", ExpiresAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}).ExecCredential())
+	assert.Nil(t, err)
+	assert.Contains(t, string(credential), `"expirationTimestamp":"2025-01-02T03:04:05Z"`)
+}
@@ -0,0 +1,80 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ConfigureTracing exports the registration spans via OTLP/HTTP to endpoint,
+// e.g. http://localhost:4318. Tracing is disabled when endpoint is empty. The returned function flushes
+// and stops the exporter.
+func ConfigureTracing(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil || endpointUrl.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q", endpoint)
+	}
+	// As for OTEL_EXPORTER_OTLP_ENDPOINT, a base URL gets the signal path appended
+	if strings.Trim(endpointUrl.Path, "/") == "" {
+		endpointUrl.Path = OtlpTracesPath
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpointUrl.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(TracerName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// injectTraceContext adds the W3C traceparent and tracestate headers of the
+// current span to the request.
+func injectTraceContext(ctx context.Context, req *http.Request) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+}
+
+// setTraceExtension adds the CloudEvents distributed tracing extension
+// carrying the current span context to the event.
+func setTraceExtension(ctx context.Context, cloudEvent *cloudevents.Event) {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	if traceParent := carrier.Get(TraceParentExtension); traceParent != "" {
+		cloudEvent.SetExtension(TraceParentExtension, traceParent)
+		if traceState := carrier.Get(TraceStateExtension); traceState != "" {
+			cloudEvent.SetExtension(TraceStateExtension, traceState)
+		}
+	}
+}
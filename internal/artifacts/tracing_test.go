@@ -0,0 +1,97 @@
+package artifacts
+
+import (
+	"context"
+	"gha-register-build-artifact/internal/platformtest"
+	"gha-register-build-artifact/internal/platformtest/testserver"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// resetTracing restores the no-op tracer provider and propagator after the
+// test. Restoring the original global provider does not work once it
+// delegates to another provider.
+func resetTracing(t *testing.T) {
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+}
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	resetTracing(t)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	_, err := ConfigureTracing(context.Background(), "")
+	assert.Nil(t, err)
+	return recorder
+}
+
+func spanNames(recorder *tracetest.SpanRecorder) []string {
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	return names
+}
+
+func TestTracing(t *testing.T) {
+
+	t.Run("Spans and propagation", func(t *testing.T) {
+		recorder := recordSpans(t)
+		server := testserver.New(t, platformtest.Config{})
+		setTestEnv(t, server)
+
+		config := Config{}
+		_, err := config.Run(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, []string{StepValidate, StepOidc, StepTokenExchange, StepSendEvent, SpanRegister}, spanNames(recorder))
+
+		root := recorder.Ended()[4]
+		event := server.Events()[0]
+		traceParent, _ := event.Extensions()[TraceParentExtension].(string)
+		assert.Contains(t, traceParent, root.SpanContext().TraceID().String())
+		assert.Contains(t, server.Header(platformtest.EventsEndpoint).Get(TraceParentExtension), root.SpanContext().TraceID().String())
+		assert.Contains(t, server.Header(platformtest.TokenExchangeEndpoint).Get(TraceParentExtension), root.SpanContext().TraceID().String())
+	})
+
+	t.Run("Failed step", func(t *testing.T) {
+		recorder := recordSpans(t)
+		server := testserver.New(t, platformtest.Config{Faults: map[string]platformtest.Fault{
+			platformtest.EventsEndpoint: {Status: http.StatusBadGateway},
+		}})
+		setTestEnv(t, server)
+
+		config := Config{}
+		_, err := config.Run(context.Background())
+		assert.NotNil(t, err)
+		for _, span := range recorder.Ended() {
+			if span.Name() == StepSendEvent || span.Name() == SpanRegister {
+				assert.Equal(t, codes.Error, span.Status().Code)
+			} else {
+				assert.Equal(t, codes.Unset, span.Status().Code)
+			}
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		config := provenanceConfig()
+		cloudEvent, _ := prepareCloudEvent(config, prepareCloudEventData(config))
+		setTraceExtension(context.Background(), &cloudEvent)
+		assert.Empty(t, cloudEvent.Extensions())
+	})
+
+	t.Run("Invalid endpoint", func(t *testing.T) {
+		resetTracing(t)
+		_, err := ConfigureTracing(context.Background(), "localhost")
+		assert.Equal(t, `invalid OTLP endpoint "localhost"`, err.Error())
+	})
+}
@@ -0,0 +1,53 @@
+package platformtest
+
+import (
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+)
+
+// Artifact is an artifact registration stored by the fake platform.
+type Artifact struct {
+	Id              string    `json:"id"`
+	EventId         string    `json:"event_id"`
+	Subject         string    `json:"subject"`
+	Source          string    `json:"source"`
+	ArtifactName    string    `json:"artifact_name"`
+	ArtifactVersion string    `json:"artifact_version"`
+	ArtifactUrl     string    `json:"artifact_url,omitempty"`
+	ArtifactType    string    `json:"artifact_type,omitempty"`
+	ArtifactDigest  string    `json:"artifact_digest,omitempty"`
+	ArtifactLabel   string    `json:"artifact_label,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func newArtifact(event cloudevents.Event) (Artifact, error) {
+	var data struct {
+		ArtifactInfo struct {
+			ArtifactName    string `json:"artifact_name"`
+			ArtifactUrl     string `json:"artifact_url"`
+			ArtifactVersion string `json:"artifact_version"`
+			ArtifactType    string `json:"artifact_type"`
+			ArtifactDigest  string `json:"artifact_digest"`
+			ArtifactLabel   string `json:"artifact_label"`
+		} `json:"artifact_info"`
+	}
+	if err := event.DataAs(&data); err != nil {
+		return Artifact{}, err
+	}
+	info := data.ArtifactInfo
+	return Artifact{
+		Id:              uuid.NewString(),
+		EventId:         event.ID(),
+		Subject:         event.Subject(),
+		Source:          event.Source(),
+		ArtifactName:    info.ArtifactName,
+		ArtifactVersion: info.ArtifactVersion,
+		ArtifactUrl:     info.ArtifactUrl,
+		ArtifactType:    info.ArtifactType,
+		ArtifactDigest:  info.ArtifactDigest,
+		ArtifactLabel:   info.ArtifactLabel,
+		CreatedAt:       time.Now().UTC(),
+	}, nil
+}
@@ -0,0 +1,218 @@
+package platformtest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WorkflowRun is a workflow run attempt served by the GitHub API stand-in.
+type WorkflowRun struct {
+	// Repository is the owner/repo of the run.
+	Repository string    `json:"repository"`
+	Id         int64     `json:"id"`
+	Attempt    int       `json:"attempt"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// ActionsArtifact is a workflow artifact served by the GitHub API stand-in.
+type ActionsArtifact struct {
+	Repository string `json:"repository"`
+	Id         int64  `json:"id"`
+	Name       string `json:"name"`
+	RunId      int64  `json:"run_id"`
+	Expired    bool   `json:"expired"`
+}
+
+// PackageVersion is a container package version served by the GitHub API
+// stand-in, for both organization and user owners.
+type PackageVersion struct {
+	Owner     string    `json:"owner"`
+	Package   string    `json:"package"`
+	Id        int64     `json:"id"`
+	Digest    string    `json:"digest"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Release is a GitHub release served by the GitHub API stand-in.
+type Release struct {
+	Repository string         `json:"repository"`
+	Id         int64          `json:"id"`
+	Tag        string         `json:"tag"`
+	Name       string         `json:"name"`
+	Prerelease bool           `json:"prerelease"`
+	Assets     []ReleaseAsset `json:"assets"`
+}
+
+// ReleaseAsset is an asset of a release. The digest of Content is only
+// returned when WithDigest is set, as older GitHub Enterprise Server versions
+// do not return asset digests.
+type ReleaseAsset struct {
+	Id         int64  `json:"id"`
+	Name       string `json:"name"`
+	Content    string `json:"content"`
+	WithDigest bool   `json:"with-digest"`
+	// State is uploaded unless set, e.g. to starter for an incomplete upload.
+	State string `json:"state,omitempty"`
+}
+
+// Digest returns the sha256 digest of the asset content.
+func (a ReleaseAsset) Digest() string {
+	sum := sha256.Sum256([]byte(a.Content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func (p *Platform) handleGithub() {
+	p.mux.HandleFunc("GET "+GithubRunAttemptPath, p.withFault(GithubEndpoint, p.withGithubToken(p.handleWorkflowRun)))
+	p.mux.HandleFunc("GET "+GithubArtifactPath, p.withFault(GithubEndpoint, p.withGithubToken(p.handleActionsArtifact)))
+	p.mux.HandleFunc("GET "+GithubReleasePath, p.withFault(GithubEndpoint, p.withGithubToken(p.handleRelease)))
+	p.mux.HandleFunc("GET "+GithubAssetPath, p.withFault(GithubEndpoint, p.withGithubToken(p.handleReleaseAsset)))
+	p.mux.HandleFunc("GET /orgs/{owner}/packages/container/{package}/versions", p.withFault(GithubEndpoint, p.withGithubToken(p.handlePackageVersions)))
+	p.mux.HandleFunc("GET /users/{owner}/packages/container/{package}/versions", p.withFault(GithubEndpoint, p.withGithubToken(p.handlePackageVersions)))
+}
+
+func (p *Platform) withGithubToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if p.config.GithubToken != "" && bearerToken(r) != p.config.GithubToken {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (p *Platform) handleWorkflowRun(w http.ResponseWriter, r *http.Request) {
+	repository := r.PathValue("owner") + "/" + r.PathValue("repo")
+	for _, run := range p.config.WorkflowRuns {
+		if run.Repository == repository && fmt.Sprint(run.Id) == r.PathValue("run_id") && strconv.Itoa(run.Attempt) == r.PathValue("attempt") {
+			writeJSON(w, http.StatusOK, map[string]any{
+				"id":             run.Id,
+				"run_attempt":    run.Attempt,
+				"status":         run.Status,
+				"run_started_at": run.StartedAt,
+			})
+			return
+		}
+	}
+	writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+}
+
+func (p *Platform) handleActionsArtifact(w http.ResponseWriter, r *http.Request) {
+	repository := r.PathValue("owner") + "/" + r.PathValue("repo")
+	for _, artifact := range p.config.ActionsArtifacts {
+		if artifact.Repository == repository && fmt.Sprint(artifact.Id) == r.PathValue("artifact_id") {
+			writeJSON(w, http.StatusOK, map[string]any{
+				"id":           artifact.Id,
+				"name":         artifact.Name,
+				"expired":      artifact.Expired,
+				"workflow_run": map[string]any{"id": artifact.RunId},
+			})
+			return
+		}
+	}
+	writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+}
+
+func (p *Platform) handlePackageVersions(w http.ResponseWriter, r *http.Request) {
+	versions := []map[string]any{}
+	for _, version := range p.config.PackageVersions {
+		if version.Owner == r.PathValue("owner") && version.Package == r.PathValue("package") {
+			versions = append(versions, map[string]any{
+				"id":         version.Id,
+				"name":       version.Digest,
+				"created_at": version.CreatedAt,
+				"metadata": map[string]any{
+					"package_type": "container",
+					"container":    map[string]any{"tags": append([]string{}, version.Tags...)},
+				},
+			})
+		}
+	}
+	if len(versions) == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Package not found."})
+		return
+	}
+	writeJSON(w, http.StatusOK, paginate(w, r, versions))
+}
+
+// paginate returns the page of the items selected by the page and per_page
+// parameters and links the next page like the GitHub API.
+func paginate[T any](w http.ResponseWriter, r *http.Request, items []T) []T {
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage <= 0 {
+		perPage = 30
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	if end < len(items) {
+		query := r.URL.Query()
+		query.Set("page", strconv.Itoa(page+1))
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?%s>; rel="next"`, r.Host, r.URL.Path, query.Encode()))
+	}
+	return items[start:end]
+}
+
+func (p *Platform) handleRelease(w http.ResponseWriter, r *http.Request) {
+	repository := r.PathValue("owner") + "/" + r.PathValue("repo")
+	for _, release := range p.config.Releases {
+		if release.Repository != repository || release.Tag != r.PathValue("tag") {
+			continue
+		}
+		assets := []map[string]any{}
+		for _, asset := range release.Assets {
+			state := asset.State
+			if state == "" {
+				state = "uploaded"
+			}
+			value := map[string]any{
+				"id":                   asset.Id,
+				"name":                 asset.Name,
+				"size":                 len(asset.Content),
+				"state":                state,
+				"url":                  fmt.Sprintf("http://%s/repos/%s/releases/assets/%d", r.Host, repository, asset.Id),
+				"browser_download_url": fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", repository, release.Tag, asset.Name),
+			}
+			if asset.WithDigest {
+				value["digest"] = asset.Digest()
+			}
+			assets = append(assets, value)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"id":         release.Id,
+			"tag_name":   release.Tag,
+			"name":       release.Name,
+			"prerelease": release.Prerelease,
+			"html_url":   fmt.Sprintf("https://github.com/%s/releases/tag/%s", repository, release.Tag),
+			"assets":     assets,
+		})
+		return
+	}
+	writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+}
+
+func (p *Platform) handleReleaseAsset(w http.ResponseWriter, r *http.Request) {
+	repository := r.PathValue("owner") + "/" + r.PathValue("repo")
+	for _, release := range p.config.Releases {
+		for _, asset := range release.Assets {
+			if release.Repository == repository && fmt.Sprint(asset.Id) == r.PathValue("asset_id") {
+				if r.Header.Get("Accept") != "application/octet-stream" {
+					writeJSON(w, http.StatusOK, map[string]any{"id": asset.Id, "name": asset.Name})
+					return
+				}
+				w.Header().Set("Content-Type", "application/octet-stream")
+				_, _ = w.Write([]byte(asset.Content))
+				return
+			}
+		}
+	}
+	writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+}
@@ -0,0 +1,437 @@
+// Package platformtest provides a fake CloudBees platform for development and
+// tests. It implements the GitHub Actions OIDC token endpoint, the CloudBees
+// token exchange and the external events endpoint, records the received
+// events and can inject faults into any of them. It also stands in for the
+// GitHub API used to verify workflow runs and the artifacts they published
+// and to read releases, and for an OCI registry serving image indexes.
+package platformtest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	OIDCPath          = "/oidc/token"
+	JWKSPath          = "/.well-known/jwks"
+	OpenIDConfigPath  = "/.well-known/openid-configuration"
+	TokenExchangePath = "/token-exchange/external-oidc-id-token"
+	EventsPath        = "/v3/external-events"
+
+	GithubRunAttemptPath = "/repos/{owner}/{repo}/actions/runs/{run_id}/attempts/{attempt}"
+	GithubArtifactPath   = "/repos/{owner}/{repo}/actions/artifacts/{artifact_id}"
+	GithubReleasePath    = "/repos/{owner}/{repo}/releases/tags/{tag}"
+	GithubAssetPath      = "/repos/{owner}/{repo}/releases/assets/{asset_id}"
+	RegistryTokenPath    = "/v2/token"
+
+	OIDCEndpoint          = "oidc"
+	TokenExchangeEndpoint = "token-exchange"
+	EventsEndpoint        = "events"
+	GithubEndpoint        = "github"
+	RegistryEndpoint      = "registry"
+
+	DefaultIssuer       = "https://token.actions.githubusercontent.com"
+	DefaultSubject      = "repo:owner/repo:ref:refs/heads/main"
+	DefaultRequestToken = "mock-request-token"
+
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	MediaTypeOciIndex    = "application/vnd.oci.image.index.v1+json"
+	MediaTypeOciManifest = "application/vnd.oci.image.manifest.v1+json"
+	RequestIdHeader      = "X-Request-Id"
+
+	keyId         = "platformtest"
+	registryToken = "mock-registry-token"
+)
+
+// Config configures the fake platform. Zero values fall back to defaults.
+type Config struct {
+	// Issuer is the iss claim of the issued OIDC tokens.
+	Issuer string `json:"issuer,omitempty"`
+	// Subject is the sub claim of the issued OIDC tokens.
+	Subject string `json:"subject,omitempty"`
+	// RequestToken is the bearer token expected by the OIDC endpoint, as
+	// ACTIONS_ID_TOKEN_REQUEST_TOKEN. Empty accepts any token.
+	RequestToken string `json:"request-token,omitempty"`
+	// Providers are accepted by the token exchange. Empty accepts GITHUB
+	// only, the provider documented by the platform.
+	Providers []string `json:"providers,omitempty"`
+	// Faults are keyed by endpoint: oidc, token-exchange, events,
+	// github or registry.
+	Faults map[string]Fault `json:"faults,omitempty"`
+	// GithubToken is the token expected by the GitHub API stand-in. Empty
+	// accepts any token.
+	GithubToken string `json:"github-token,omitempty"`
+	// WorkflowRuns, ActionsArtifacts, PackageVersions and Releases are served
+	// by the GitHub API stand-in.
+	WorkflowRuns     []WorkflowRun     `json:"workflow-runs,omitempty"`
+	ActionsArtifacts []ActionsArtifact `json:"actions-artifacts,omitempty"`
+	PackageVersions  []PackageVersion  `json:"package-versions,omitempty"`
+	Releases         []Release         `json:"releases,omitempty"`
+	// Images are served by the OCI registry stand-in.
+	Images []Image `json:"images,omitempty"`
+}
+
+// Fault replaces or delays the normal response of an endpoint.
+type Fault struct {
+	// Latency delays the response, e.g. "500ms".
+	Latency Duration `json:"latency,omitempty"`
+	// Status replaces the response status code, e.g. 401 or 503.
+	Status int `json:"status,omitempty"`
+	// Body replaces the response body, e.g. with malformed JSON.
+	Body string `json:"body,omitempty"`
+	// Count limits the fault to the first Count requests. Zero means every request.
+	Count int `json:"count,omitempty"`
+	// After lets the first After requests through before the fault applies.
+	After int `json:"after,omitempty"`
+}
+
+// Duration is a time.Duration encoded as a string such as "1s" in JSON.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Platform is the http.Handler of the fake platform.
+type Platform struct {
+	config Config
+	key    *rsa.PrivateKey
+	mux    *http.ServeMux
+
+	mu              sync.Mutex
+	requests        map[string]int
+	headers         map[string]http.Header
+	accessTokens    map[string]bool
+	idempotencyKeys map[string]string
+	events          []cloudevents.Event
+	artifacts       []Artifact
+	onEvent         func(cloudevents.Event)
+}
+
+// NewPlatform creates the fake platform handler with a fresh signing key.
+func NewPlatform(config Config) (*Platform, error) {
+	if config.Issuer == "" {
+		config.Issuer = DefaultIssuer
+	}
+	if config.Subject == "" {
+		config.Subject = DefaultSubject
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	platform := &Platform{
+		config:          config,
+		key:             key,
+		mux:             http.NewServeMux(),
+		requests:        map[string]int{},
+		headers:         map[string]http.Header{},
+		accessTokens:    map[string]bool{},
+		idempotencyKeys: map[string]string{},
+	}
+	platform.mux.HandleFunc("GET "+OIDCPath, platform.withFault(OIDCEndpoint, platform.handleOIDC))
+	platform.mux.HandleFunc("GET "+JWKSPath, platform.handleJWKS)
+	platform.mux.HandleFunc("GET "+OpenIDConfigPath, platform.handleOpenIDConfig)
+	platform.mux.HandleFunc("POST "+TokenExchangePath, platform.withFault(TokenExchangeEndpoint, platform.handleTokenExchange))
+	platform.mux.HandleFunc("POST "+EventsPath, platform.withFault(EventsEndpoint, platform.handleEvent))
+	platform.handleGithub()
+	platform.mux.HandleFunc("GET "+RegistryTokenPath, platform.withFault(RegistryEndpoint, platform.handleRegistryToken))
+	platform.mux.HandleFunc("GET /v2/{path...}", platform.withFault(RegistryEndpoint, platform.handleManifest))
+	return platform, nil
+}
+
+func (p *Platform) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(RequestIdHeader, uuid.NewString())
+	p.mux.ServeHTTP(w, r)
+}
+
+// Handle registers an additional handler, e.g. to fake further platform APIs.
+func (p *Platform) Handle(pattern string, handler http.HandlerFunc) {
+	p.mux.HandleFunc(pattern, handler)
+}
+
+// Events returns the events received so far.
+func (p *Platform) Events() []cloudevents.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]cloudevents.Event(nil), p.events...)
+}
+
+// OnEvent registers a callback invoked for every event received.
+func (p *Platform) OnEvent(callback func(cloudevents.Event)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onEvent = callback
+}
+
+// Requests returns the number of requests received by an endpoint.
+func (p *Platform) Requests(endpoint string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests[endpoint]
+}
+
+// Header returns the headers of the last request received by an endpoint.
+func (p *Platform) Header(endpoint string) http.Header {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.headers[endpoint]
+}
+
+// IssueToken returns an OIDC token signed by the platform for the audience.
+func (p *Platform) IssueToken(audience string) (string, error) {
+	now := time.Now()
+	return p.sign(map[string]any{
+		"iss": p.config.Issuer,
+		"sub": p.config.Subject,
+		"aud": audience,
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+		"jti": uuid.NewString(),
+	})
+}
+
+// Artifacts returns the artifacts registered so far.
+func (p *Platform) Artifacts() []Artifact {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Artifact(nil), p.artifacts...)
+}
+
+// Issuer returns the iss claim of the issued OIDC tokens.
+func (p *Platform) Issuer() string {
+	return p.config.Issuer
+}
+
+func (p *Platform) withFault(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		p.requests[endpoint]++
+		p.headers[endpoint] = r.Header.Clone()
+		count := p.requests[endpoint]
+		p.mu.Unlock()
+
+		fault, ok := p.config.Faults[endpoint]
+		if !ok || count <= fault.After || (fault.Count > 0 && count > fault.After+fault.Count) {
+			next(w, r)
+			return
+		}
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(time.Duration(fault.Latency)):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.Status == 0 && fault.Body == "" {
+			next(w, r)
+			return
+		}
+		status := fault.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(fault.Body))
+	}
+}
+
+func (p *Platform) handleOIDC(w http.ResponseWriter, r *http.Request) {
+	if p.config.RequestToken != "" && bearerToken(r) != p.config.RequestToken {
+		writeError(w, http.StatusUnauthorized, "invalid request token")
+		return
+	}
+	audience := r.URL.Query().Get("audience")
+	token, err := p.IssueToken(audience)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"value": token})
+}
+
+func (p *Platform) handleJWKS(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": keyId,
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *Platform) handleOpenIDConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":   p.config.Issuer,
+		"jwks_uri": "http://" + r.Host + JWKSPath,
+	})
+}
+
+func (p *Platform) handleTokenExchange(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Provider  string `json:"provider"`
+		Audience  string `json:"audience"`
+		ServerUrl string `json:"serverUrl"`
+		Issuer    string `json:"issuer"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid token exchange request")
+		return
+	}
+	claims, err := p.verify(bearerToken(r))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	providers := p.config.Providers
+	if len(providers) == 0 {
+		providers = []string{"GITHUB"}
+	}
+	if !slices.Contains(providers, request.Provider) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unsupported provider %q", request.Provider))
+		return
+	}
+	if request.Issuer != "" && claims["iss"] != request.Issuer {
+		writeError(w, http.StatusUnauthorized, fmt.Sprintf("OIDC token issuer %v does not match %s", claims["iss"], request.Issuer))
+		return
+	}
+	accessToken := "mock-cbp-token-" + uuid.NewString()
+	p.mu.Lock()
+	p.accessTokens[accessToken] = true
+	p.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]string{"accessToken": accessToken})
+}
+
+func (p *Platform) handleEvent(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	authorized := p.accessTokens[bearerToken(r)]
+	p.mu.Unlock()
+	if !authorized {
+		writeError(w, http.StatusUnauthorized, "invalid access token")
+		return
+	}
+	event := cloudevents.NewEvent()
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid CloudEvent: "+err.Error())
+		return
+	}
+	if err := event.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid CloudEvent: "+err.Error())
+		return
+	}
+	artifact, err := newArtifact(event)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid CloudEvent data: "+err.Error())
+		return
+	}
+	p.mu.Lock()
+	// A replayed idempotency key is accepted without recording the event again.
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+		if id, ok := p.idempotencyKeys[key]; ok {
+			p.mu.Unlock()
+			writeJSON(w, http.StatusOK, map[string]any{"id": id})
+			return
+		}
+		p.idempotencyKeys[key] = artifact.Id
+	}
+	p.events = append(p.events, event)
+	p.artifacts = append(p.artifacts, artifact)
+	onEvent := p.onEvent
+	p.mu.Unlock()
+	if onEvent != nil {
+		onEvent(event)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": artifact.Id})
+}
+
+func (p *Platform) sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyId})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (p *Platform) verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed OIDC token")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed OIDC token signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&p.key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, errors.New("invalid OIDC token signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed OIDC token payload")
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.New("malformed OIDC token payload")
+	}
+	if exp, _ := claims["exp"].(float64); time.Now().Unix() > int64(exp) {
+		return nil, errors.New("OIDC token expired")
+	}
+	return claims, nil
+}
+
+func bearerToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"code": status, "message": message})
+}
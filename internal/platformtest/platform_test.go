@@ -0,0 +1,122 @@
+package platformtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type testServer struct {
+	*httptest.Server
+	*Platform
+}
+
+func newServer(t *testing.T, config Config) *testServer {
+	platform, err := NewPlatform(config)
+	assert.Nil(t, err)
+	server := &testServer{Server: httptest.NewServer(platform), Platform: platform}
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *testServer) OIDCUrl() string {
+	return s.URL + OIDCPath
+}
+
+func exchangeToken(t *testing.T, server *testServer, oidcToken string) *http.Response {
+	req, _ := http.NewRequest(http.MethodPost, server.URL+TokenExchangePath, strings.NewReader(`{"provider":"GITHUB"}`))
+	req.Header.Set("Authorization", "Bearer "+oidcToken)
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	return resp
+}
+
+func TestPlatform(t *testing.T) {
+
+	t.Run("Register event", func(t *testing.T) {
+		server := newServer(t, Config{RequestToken: DefaultRequestToken})
+
+		req, _ := http.NewRequest(http.MethodGet, server.OIDCUrl()+"?audience=test", nil)
+		req.Header.Set("Authorization", "Bearer "+DefaultRequestToken)
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var oidcResp struct{ Value string }
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&oidcResp))
+		claims, err := server.verify(oidcResp.Value)
+		assert.Nil(t, err)
+		assert.Equal(t, DefaultIssuer, claims["iss"])
+		assert.Equal(t, "test", claims["aud"])
+
+		resp = exchangeToken(t, server, oidcResp.Value)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var tokenResp struct{ AccessToken string }
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&tokenResp))
+
+		event := cloudevents.NewEvent()
+		event.SetID("id")
+		event.SetType("type")
+		event.SetSource("source")
+		eventJSON, _ := json.Marshal(event)
+		req, _ = http.NewRequest(http.MethodPost, server.URL+EventsPath, bytes.NewReader(eventJSON))
+		req.Header.Set("Authorization", "Bearer "+tokenResp.AccessToken)
+		resp, err = http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Len(t, server.Events(), 1)
+		assert.Equal(t, "id", server.Events()[0].ID())
+		assert.Equal(t, 1, server.Requests(EventsEndpoint))
+	})
+
+	t.Run("Invalid request token", func(t *testing.T) {
+		server := newServer(t, Config{RequestToken: DefaultRequestToken})
+		resp, err := http.Get(server.OIDCUrl())
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Invalid OIDC token", func(t *testing.T) {
+		server := newServer(t, Config{})
+		other := newServer(t, Config{})
+		token, _ := other.IssueToken("test")
+		resp := exchangeToken(t, server, token)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Invalid access token", func(t *testing.T) {
+		server := newServer(t, Config{})
+		resp, err := http.Post(server.URL+EventsPath, "application/json", strings.NewReader("{}"))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Empty(t, server.Events())
+	})
+
+	t.Run("Fault", func(t *testing.T) {
+		server := newServer(t, Config{Faults: map[string]Fault{
+			OIDCEndpoint: {Status: http.StatusServiceUnavailable, Body: "{", Count: 1, Latency: Duration(10 * time.Millisecond)},
+		}})
+		start := time.Now()
+		resp, err := http.Get(server.OIDCUrl())
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+
+		resp, err = http.Get(server.OIDCUrl())
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Fault config", func(t *testing.T) {
+		var config Config
+		err := json.Unmarshal([]byte(`{"faults": {"events": {"latency": "1s", "status": 401}}}`), &config)
+		assert.Nil(t, err)
+		assert.Equal(t, Fault{Latency: Duration(time.Second), Status: http.StatusUnauthorized}, config.Faults[EventsEndpoint])
+	})
+}
@@ -0,0 +1,116 @@
+package platformtest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Image is an image served by the OCI registry stand-in. An image with
+// platforms is served as an image index of one image per platform plus an
+// attestation manifest, otherwise as a single image manifest.
+type Image struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	// Platforms are os/arch[/variant] values, e.g. linux/arm64/v8.
+	Platforms []string `json:"platforms,omitempty"`
+}
+
+// ImageManifest is a manifest of an image with its digest.
+type ImageManifest struct {
+	MediaType string
+	Digest    string
+	Content   []byte
+	// Platforms are the digests of the platform images of an index, keyed by platform.
+	Platforms map[string]string
+}
+
+// Manifest returns the manifest served for an image.
+func (image Image) Manifest() ImageManifest {
+	if len(image.Platforms) == 0 {
+		return newManifest(MediaTypeOciManifest, map[string]any{
+			"schemaVersion": 2,
+			"mediaType":     MediaTypeOciManifest,
+			"annotations":   map[string]string{"org.opencontainers.image.ref.name": image.Repository + ":" + image.Tag},
+		})
+	}
+	platforms := map[string]string{}
+	manifests := []map[string]any{}
+	for _, platform := range append(append([]string{}, image.Platforms...), "unknown/unknown") {
+		manifest := image.platformManifest(platform)
+		parts := strings.SplitN(platform, "/", 3)
+		descriptor := map[string]any{"architecture": parts[1], "os": parts[0]}
+		if len(parts) == 3 {
+			descriptor["variant"] = parts[2]
+		}
+		manifests = append(manifests, map[string]any{
+			"mediaType": MediaTypeOciManifest,
+			"digest":    manifest.Digest,
+			"size":      len(manifest.Content),
+			"platform":  descriptor,
+		})
+		if platform != "unknown/unknown" {
+			platforms[platform] = manifest.Digest
+		}
+	}
+	index := newManifest(MediaTypeOciIndex, map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     MediaTypeOciIndex,
+		"manifests":     manifests,
+	})
+	index.Platforms = platforms
+	return index
+}
+
+func (image Image) platformManifest(platform string) ImageManifest {
+	return newManifest(MediaTypeOciManifest, map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     MediaTypeOciManifest,
+		"annotations":   map[string]string{"org.opencontainers.image.ref.name": image.Repository + ":" + image.Tag, "platform": platform},
+	})
+}
+
+func newManifest(mediaType string, manifest map[string]any) ImageManifest {
+	content, _ := json.Marshal(manifest)
+	sum := sha256.Sum256(content)
+	return ImageManifest{MediaType: mediaType, Digest: "sha256:" + hex.EncodeToString(sum[:]), Content: content}
+}
+
+func (p *Platform) handleRegistryToken(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"token": registryToken})
+}
+
+// handleManifest serves GET /v2/<repository>/manifests/<reference> after the
+// bearer token challenge of the registry token service.
+func (p *Platform) handleManifest(w http.ResponseWriter, r *http.Request) {
+	repository, reference, found := strings.Cut(r.PathValue("path"), "/manifests/")
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]any{"errors": []map[string]string{{"code": "NAME_UNKNOWN"}}})
+		return
+	}
+	if bearerToken(r) != registryToken {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+r.Host+RegistryTokenPath+`",service="platformtest",scope="repository:`+repository+`:pull"`)
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"errors": []map[string]string{{"code": "UNAUTHORIZED"}}})
+		return
+	}
+	for _, image := range p.config.Images {
+		if image.Repository != repository {
+			continue
+		}
+		manifests := []ImageManifest{image.Manifest()}
+		for _, platform := range append(append([]string{}, image.Platforms...), "unknown/unknown") {
+			manifests = append(manifests, image.platformManifest(platform))
+		}
+		for i, manifest := range manifests {
+			if (i == 0 && reference == image.Tag) || reference == manifest.Digest {
+				w.Header().Set("Content-Type", manifest.MediaType)
+				w.Header().Set("Docker-Content-Digest", manifest.Digest)
+				_, _ = w.Write(manifest.Content)
+				return
+			}
+		}
+	}
+	writeJSON(w, http.StatusNotFound, map[string]any{"errors": []map[string]string{{"code": "MANIFEST_UNKNOWN"}}})
+}
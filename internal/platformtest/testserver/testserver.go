@@ -0,0 +1,45 @@
+// Package testserver runs the fake platform of package platformtest on a
+// local httptest server for the duration of a test.
+package testserver
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gha-register-build-artifact/internal/platformtest"
+)
+
+// Server is a fake platform listening on a local httptest server.
+type Server struct {
+	*httptest.Server
+	*platformtest.Platform
+}
+
+// New starts a fake platform for the duration of a test.
+func New(t testing.TB, config platformtest.Config) *Server {
+	t.Helper()
+	platform, err := platformtest.NewPlatform(config)
+	if err != nil {
+		t.Fatalf("failed to create platform: %v", err)
+	}
+	server := &Server{Server: httptest.NewServer(platform), Platform: platform}
+	t.Cleanup(server.Close)
+	return server
+}
+
+// OIDCUrl is the value to use for ACTIONS_ID_TOKEN_REQUEST_URL.
+func (s *Server) OIDCUrl() string {
+	return s.URL + platformtest.OIDCPath
+}
+
+// RegistryHost is the registry of image references served by the stand-in,
+// e.g. RegistryHost()+"/owner/app:1.0.0".
+func (s *Server) RegistryHost() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+// GithubApiUrl is the value to use for GITHUB_API_URL.
+func (s *Server) GithubApiUrl() string {
+	return s.URL
+}
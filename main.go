@@ -2,12 +2,15 @@ package main
 
 import (
 	"gha-register-build-artifact/cmd"
-	"log"
+	"gha-register-build-artifact/internal/artifacts"
+	"log/slog"
+	"os"
 )
 
 func main() {
 
 	if err := cmd.Execute(); err != nil {
-		log.Fatal(err)
+		slog.Error(err.Error(), artifacts.ErrorLogAttrs(err)...)
+		os.Exit(artifacts.ExitCode(err))
 	}
 }